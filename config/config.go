@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 var once sync.Once
@@ -19,6 +22,42 @@ type Configuration struct {
 	RabbitMQHost  string
 	RabbitMQPort  string
 	SecurityToken string
+
+	IdempotencyTTL time.Duration
+	MaxBatchSize   int
+	MaxOrderTotal  float64
+
+	APIKeysRequired      bool
+	AuthorizationEnabled bool
+	RateLimits           map[string]string
+	Bulkheads            map[string]string
+	Timeouts             map[string]time.Duration
+
+	MaxBodySize               int64
+	MaxImportSize             int64
+	JSONDisallowUnknownFields bool
+
+	LogFormat string
+	LogLevel  string
+
+	LogRedactKeys []string
+
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSClientCAFile   string
+	TLSReloadInterval time.Duration
+	TLSClientScopes   map[string][]string
+
+	CORSOrigins     []string
+	CORSCredentials bool
+	CORSHeaders     []string
+	CORSMaxAge      time.Duration
+
+	AccessLogFormat   string
+	AccessLogExclude  []string
+	AccessLogSampling map[string]float64
+
+	DocsAssetsDir string
 }
 
 var (
@@ -47,5 +86,141 @@ func loadConfig() Configuration {
 		RabbitMQHost:  os.Getenv("UP_RABBITMQ_HOST"),
 		RabbitMQPort:  os.Getenv("UP_RABBITMQ_PORT"),
 		SecurityToken: os.Getenv("UP_SECURITY_SECRET"),
+
+		IdempotencyTTL: getDuration("UP_IDEMPOTENCY_TTL", 24*time.Hour),
+		MaxBatchSize:   getInt("UP_BATCH_MAX_SIZE", 100),
+		MaxOrderTotal:  getFloat("UP_ORDER_MAX_TOTAL", 0),
+
+		APIKeysRequired:      os.Getenv("UP_API_KEYS_REQUIRED") == "true",
+		AuthorizationEnabled: os.Getenv("UP_AUTHORIZATION_ENABLED") == "true",
+		RateLimits:           getMap("UP_RATE_LIMITS"),
+		Bulkheads:            getMap("UP_BULKHEADS"),
+		Timeouts:             getDurations("UP_TIMEOUTS"),
+
+		MaxBodySize:               int64(getInt("UP_MAX_BODY_SIZE", 1<<20)),
+		MaxImportSize:             int64(getInt("UP_MAX_IMPORT_SIZE", 32<<20)),
+		JSONDisallowUnknownFields: os.Getenv("UP_JSON_DISALLOW_UNKNOWN_FIELDS") != "false",
+
+		LogFormat: os.Getenv("UP_LOG_FORMAT"),
+		LogLevel:  os.Getenv("UP_LOG_LEVEL"),
+
+		LogRedactKeys: getList("UP_LOG_REDACT_KEYS"),
+
+		TLSCertFile:       os.Getenv("UP_TLS_CERT_FILE"),
+		TLSKeyFile:        os.Getenv("UP_TLS_KEY_FILE"),
+		TLSClientCAFile:   os.Getenv("UP_TLS_CLIENT_CA_FILE"),
+		TLSReloadInterval: getDuration("UP_TLS_RELOAD_INTERVAL", time.Minute),
+		TLSClientScopes:   getScopes("UP_TLS_CLIENT_SCOPES"),
+
+		CORSOrigins:     getListOr("UP_CORS_ORIGINS", []string{"*"}),
+		CORSCredentials: os.Getenv("UP_CORS_CREDENTIALS") == "true",
+		CORSHeaders:     getList("UP_CORS_HEADERS"),
+		CORSMaxAge:      getDuration("UP_CORS_MAX_AGE", 10*time.Minute),
+
+		AccessLogFormat:   os.Getenv("UP_ACCESS_LOG_FORMAT"),
+		AccessLogExclude:  getList("UP_ACCESS_LOG_EXCLUDE"),
+		AccessLogSampling: getRates("UP_ACCESS_LOG_SAMPLING"),
+
+		DocsAssetsDir: os.Getenv("UP_DOCS_ASSETS_DIR"),
+	}
+}
+
+// getDuration reads a duration env like "30s" or "24h", returning def when unset or invalid
+func getDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getInt reads an integer env, returning def when unset or invalid
+func getInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getFloat reads a decimal env, returning def when unset or invalid
+func getFloat(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getList reads a comma separated env, skipping the empty items
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getListOr reads a comma separated env, returning def when it has no items
+func getListOr(key string, def []string) []string {
+	if list := getList(key); len(list) > 0 {
+		return list
+	}
+	return def
+}
+
+// getMap reads a comma separated env of key=value items
+func getMap(key string) map[string]string {
+	values := map[string]string{}
+	for _, item := range getList(key) {
+		if i := strings.Index(item, "="); i > 0 {
+			values[strings.TrimSpace(item[:i])] = strings.TrimSpace(item[i+1:])
+		}
+	}
+	return values
+}
+
+// getDurations reads a comma separated env of key=duration items, skipping the invalid ones
+func getDurations(key string) map[string]time.Duration {
+	durations := map[string]time.Duration{}
+	for name, value := range getMap(key) {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			durations[name] = duration
+		}
+	}
+	return durations
+}
+
+// getRates reads a comma separated env of name=rate items like "/api/v1/orders=0.1",
+// skipping the invalid ones
+func getRates(key string) map[string]float64 {
+	rates := map[string]float64{}
+	for _, item := range getList(key) {
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			continue
+		}
+		rate, err := strconv.ParseFloat(item[i+1:], 64)
+		if err != nil {
+			continue
+		}
+		rates[item[:i]] = rate
+	}
+	return rates
+}
+
+// getScopes reads a comma separated env of name=scopes items, the scopes joined by "+"
+// like "pos=orders:read+orders:write"
+func getScopes(key string) map[string][]string {
+	scopes := map[string][]string{}
+	for name, value := range getMap(key) {
+		for _, scope := range strings.Split(value, "+") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes[name] = append(scopes[name], scope)
+			}
+		}
 	}
+	return scopes
 }
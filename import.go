@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"microservice_gokit_base/src/application/codec"
+	"microservice_gokit_base/src/application/importer"
+	domainSvc "microservice_gokit_base/src/domain/service"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// runImport imports the orders of a CSV or NDJSON file, writing the rejected rows
+// to a report file, and returns the exit code of the command:
+//
+//	app.bin import -file orders.csv [-format csv] [-report orders.csv.errors.csv] [-batch 100]
+func runImport(ctx context.Context, svc domainSvc.IOrderService, args []string, batchSize int, logger log.Logger) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	var (
+		file       = flags.String("file", "", "CSV or NDJSON file of orders to import")
+		format     = flags.String("format", "", "format of the file, csv or ndjson, taken from the extension when empty")
+		reportFile = flags.String("report", "", "file of the rejected rows, <file>.errors.csv when empty")
+		batch      = flags.Int("batch", batchSize, "number of orders stored at once")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *file == "" {
+		flags.Usage()
+		return 2
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+	if *reportFile == "" {
+		*reportFile = *file + ".errors.csv"
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		return 1
+	}
+	defer input.Close()
+	reader, err := codec.NewReader(*format, input)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		return 1
+	}
+
+	output, err := os.Create(*reportFile)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		return 1
+	}
+	defer output.Close()
+	report := importer.NewCSVReport(output)
+
+	summary, err := importer.NewImporter(svc, *batch).Import(ctx, reader, report)
+	if flushErr := report.Flush(); err == nil {
+		err = flushErr
+	}
+	level.Info(logger).Log("msg", "import finished", "read", summary.Read,
+		"imported", summary.Imported, "rejected", summary.Rejected, "report", *reportFile)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		return 1
+	}
+	return 0
+}
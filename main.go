@@ -9,18 +9,29 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/application/ratelimit"
 
 	appHttp "microservice_gokit_base/src/application/transport/http"
 	"microservice_gokit_base/src/domain/model"
 	domainRepo "microservice_gokit_base/src/domain/repository"
 	domainSvc "microservice_gokit_base/src/domain/service"
 	"microservice_gokit_base/src/domain/utils"
+	"microservice_gokit_base/src/infraestructure/logging"
 	infraRepo "microservice_gokit_base/src/infraestructure/repository"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	kitexpvar "github.com/go-kit/kit/metrics/expvar"
+)
+
+// build information, set at link time with -ldflags "-X main.version=..."
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
 )
 
 func main() {
@@ -32,19 +43,32 @@ func main() {
 		ctx        = context.Background()
 		apiVersion = "/api/v1/"
 		httpAddr   = ":" + config.HTTPPort
+		startTime  = time.Now()
 	)
 
-	var logger log.Logger
+	// masks the personal data of every log line
+	redactor := utils.NewDefaultRedactor(config.LogRedactKeys...)
+
+	var baseLogger, logger log.Logger
 	{
-		logger = log.NewLogfmtLogger(os.Stderr)
-		logger = log.NewSyncLogger(logger)
-		logger = level.NewFilter(logger, level.AllowDebug())
-		logger = log.With(logger,
+		baseLogger = logging.NewLogger(os.Stderr, config.LogFormat)
+		baseLogger = logging.NewRedactLogger(baseLogger, redactor)
+		baseLogger = log.NewSyncLogger(baseLogger)
+		baseLogger = log.With(baseLogger,
 			"name", "ms-base",
 			"ts", log.DefaultTimestampUTC,
-			"caller", log.DefaultCaller,
 		)
+		logger = level.NewFilter(baseLogger, logging.Allow(config.LogLevel))
+		logger = log.With(logger, "caller", log.DefaultCaller)
+	}
+
+	// levels of the components, changed at runtime by the admin endpoints
+	logLevels := logging.NewLevels(config.LogLevel,
+		logging.ComponentService, logging.ComponentRepository, logging.ComponentTransport)
+	componentLogger := func(component string) log.Logger {
+		return log.With(logLevels.Logger(component, baseLogger), "caller", log.DefaultCaller)
 	}
+	repoLogger := componentLogger(logging.ComponentRepository)
 
 	var uuidGen utils.IUUIDGenerator
 	{
@@ -60,55 +84,233 @@ func main() {
 	defer level.Info(logger).Log("msg", "service ended")
 
 	var repo domainRepo.IOrderRepository
+	var idempotencyRepo domainRepo.IIdempotencyRepository
+	var apiKeyRepo domainRepo.IAPIKeyRepository
 	{
 		if config.DB == "mongo" {
-			connection := infraRepo.GetConnectionMongo(ctx, logger)
-			r, err := infraRepo.NewOrderMongoRepository(connection, logger)
+			connection := infraRepo.GetConnectionMongo(ctx, repoLogger)
+			r, err := infraRepo.NewOrderMongoRepository(connection, repoLogger)
 			if err != nil {
 				level.Error(logger).Log("exit", err)
 				os.Exit(-1)
 			}
 			repo = r
+			ir, err := infraRepo.NewIdempotencyMongoRepository(ctx, connection.Database(), repoLogger)
+			if err != nil {
+				level.Error(logger).Log("exit", err)
+				os.Exit(-1)
+			}
+			idempotencyRepo = ir
+			kr, err := infraRepo.NewAPIKeyMongoRepository(ctx, connection.Database(), repoLogger)
+			if err != nil {
+				level.Error(logger).Log("exit", err)
+				os.Exit(-1)
+			}
+			apiKeyRepo = kr
 		} else {
 			var dbMemory = &infraRepo.DbMemory{
 				Data: []model.Order{},
 			}
-			r, err := infraRepo.NewOrderRepositoryMem(dbMemory, logger)
+			r, err := infraRepo.NewOrderRepositoryMem(dbMemory, repoLogger)
 			if err != nil {
 				level.Error(logger).Log("exit", err)
 				os.Exit(-1)
 			}
 			repo = r
+			ir, err := infraRepo.NewIdempotencyRepositoryMem(repoLogger)
+			if err != nil {
+				level.Error(logger).Log("exit", err)
+				os.Exit(-1)
+			}
+			idempotencyRepo = ir
+			kr, err := infraRepo.NewAPIKeyRepositoryMem(repoLogger)
+			if err != nil {
+				level.Error(logger).Log("exit", err)
+				os.Exit(-1)
+			}
+			apiKeyRepo = kr
 		}
 	}
 
 	// Create Order Services
 	var svc domainSvc.IOrderService
 	{
-		svc = domainSvc.NewOrderService(repo, uuidGen, dateGen, logger)
+		pipeline := domainSvc.DefaultPipeline()
+		if config.MaxOrderTotal > 0 {
+			pipeline = append(pipeline, domainSvc.PolicyStep(domainSvc.MaxOrderTotal(float32(config.MaxOrderTotal))))
+		}
+		svc = domainSvc.NewOrderService(repo, uuidGen, dateGen, componentLogger(logging.ComponentService),
+			domainSvc.WithMaxBatchSize(config.MaxBatchSize),
+			domainSvc.WithPipeline(pipeline...),
+		)
+	}
+
+	apiKeySvc := domainSvc.NewAPIKeyService(apiKeyRepo, uuidGen, dateGen, componentLogger(logging.ComponentService))
+
+	// IMPORT COMMAND
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(ctx, svc, os.Args[2:], config.MaxBatchSize, logger))
 	}
 
+	// customers, restaurant staff and admins only reach the orders they are allowed to
+	if config.AuthorizationEnabled {
+		svc = domainSvc.NewAuthorizationService(svc)
+	}
+
+	// panics recovered by the endpoints and the http server, published on /admin/vars
+	panics := kitexpvar.NewCounter("panics")
+
 	var orderHandler http.Handler
 	{
-		endpoints := endpoints.MakeOrderEndpoints(svc)
-		orderHandler = appHttp.NewHTTPOrder(endpoints, logger, apiVersion)
+		options := []endpoints.Option{
+			endpoints.WithRecovery(componentLogger(logging.ComponentTransport), panics),
+		}
+		// the limits go before the credentials are checked so guessing them is limited too
+		if len(config.RateLimits) > 0 {
+			limits := map[string]ratelimit.Limit{}
+			for name, spec := range config.RateLimits {
+				limit, err := ratelimit.ParseLimit(spec)
+				if err != nil {
+					level.Error(logger).Log("exit", err, "endpoint", name)
+					os.Exit(-1)
+				}
+				limits[name] = limit
+			}
+			options = append(options, endpoints.WithRateLimits(ratelimit.NewMemoryStore(), limits,
+				componentLogger(logging.ComponentTransport)))
+		}
+		for client, scopes := range config.TLSClientScopes {
+			if err := domainSvc.CheckScopes(scopes); err != nil {
+				level.Error(logger).Log("exit", err, "client", client)
+				os.Exit(-1)
+			}
+		}
+		options = append(options, endpoints.WithAPIKeys(apiKeySvc, endpoints.CredentialPolicy{
+			Required:     config.APIKeysRequired,
+			Secret:       []byte(config.SecurityToken),
+			ClientScopes: config.TLSClientScopes,
+		}))
+		if config.AuthorizationEnabled {
+			options = append(options, endpoints.WithPrincipal([]byte(config.SecurityToken)))
+		}
+		// the deadline wraps the bulkheads so the time queued counts against it
+		options = append(options, endpoints.WithTimeouts(config.Timeouts))
+		if len(config.Bulkheads) > 0 {
+			bulkheads := map[string]endpoints.Bulkhead{}
+			for name, spec := range config.Bulkheads {
+				bulkhead, err := endpoints.ParseBulkhead(spec)
+				if err != nil {
+					level.Error(logger).Log("exit", err, "endpoint", name)
+					os.Exit(-1)
+				}
+				bulkheads[name] = bulkhead
+			}
+			// in flight, queued and shed requests of each endpoint, published on /admin/vars
+			options = append(options, endpoints.WithBulkheads(bulkheads, func(name string) endpoints.BulkheadMetrics {
+				return endpoints.BulkheadMetrics{
+					InFlight: kitexpvar.NewGauge("in_flight_" + name),
+					Queued:   kitexpvar.NewGauge("queued_" + name),
+					Shed:     kitexpvar.NewCounter("shed_" + name),
+				}
+			}))
+		}
+		options = append(options,
+			endpoints.WithIdempotency(idempotencyRepo, config.IdempotencyTTL, componentLogger(logging.ComponentTransport)),
+			endpoints.WithImportBatchSize(config.MaxBatchSize),
+		)
+		endpoints := endpoints.MakeOrderEndpoints(svc, options...)
+		orderHandler = appHttp.NewHTTPOrder(endpoints, componentLogger(logging.ComponentTransport), apiVersion,
+			appHttp.WithBodyLimits(appHttp.BodyLimits{
+				MaxBytes:              config.MaxBodySize,
+				MaxImportBytes:        config.MaxImportSize,
+				DisallowUnknownFields: config.JSONDisallowUnknownFields,
+			}),
+		)
+	}
+
+	var adminHandler http.Handler
+	{
+		endpoints := endpoints.MakeAdminEndpoints(logLevels, apiKeySvc, []byte(config.SecurityToken))
+		adminHandler = appHttp.NewHTTPAdmin(endpoints, componentLogger(logging.ComponentTransport), appHttp.AdminPath)
 	}
 
+	infoHandler := appHttp.NewHTTPInfo(endpoints.MakeInfoEndpoint(endpoints.InstanceInfo{
+		Build:      endpoints.BuildInfo{Version: version, Commit: commit, BuildTime: buildTime},
+		Repository: repositoryBackend(config.DB),
+		Features:   enabledFeatures(config),
+		StartTime:  startTime,
+	}), componentLogger(logging.ComponentTransport))
+
+	docsHandler := appHttp.NewHTTPDocs(apiVersion, appHttp.SpecPath, appHttp.DocsPath, config.DocsAssetsDir)
+
 	mux := http.NewServeMux()
 	mux.Handle(apiVersion, orderHandler)
-	http.Handle("/", accessControl(mux))
+	mux.Handle(appHttp.AdminPath, adminHandler)
+	mux.Handle(appHttp.InfoPath, infoHandler)
+	mux.Handle(appHttp.SpecPath, docsHandler)
+	mux.Handle(appHttp.DocsPath, docsHandler)
+	mux.Handle(appHttp.DocsPath+"/", docsHandler)
+	mux.Handle("/", appHttp.NewNotFound())
+
+	corsHandler, err := appHttp.NewCORS(mux, appHttp.CORSConfig{
+		AllowedOrigins:   config.CORSOrigins,
+		AllowCredentials: config.CORSCredentials,
+		AllowedHeaders:   config.CORSHeaders,
+		MaxAge:           config.CORSMaxAge,
+	}, orderHandler, adminHandler, infoHandler, docsHandler)
+	if err != nil {
+		level.Error(logger).Log("exit", err)
+		os.Exit(-1)
+	}
+
+	var accessLogger log.Logger
+	{
+		accessLogger = logging.NewLogger(os.Stderr, config.AccessLogFormat)
+		accessLogger = logging.NewRedactLogger(accessLogger, redactor)
+		accessLogger = log.NewSyncLogger(accessLogger)
+		accessLogger = log.With(accessLogger,
+			"name", "ms-base",
+			"ts", log.DefaultTimestampUTC,
+			"log", "access",
+		)
+	}
+	recoverHandler := appHttp.NewRecover(corsHandler, componentLogger(logging.ComponentTransport), panics)
+	http.Handle("/", appHttp.NewAccessLog(recoverHandler, accessLogger,
+		appHttp.WithAccessLogExclude(config.AccessLogExclude...),
+		appHttp.WithAccessLogSampling(config.AccessLogSampling),
+	))
 
 	// INIT WEB SERVER
 	errs := make(chan error, 2)
 
+	server := &http.Server{Addr: httpAddr}
+	if config.TLSCertFile != "" {
+		certificates, err := appHttp.NewCertificateReloader(config.TLSCertFile, config.TLSKeyFile,
+			config.TLSReloadInterval, log.With(logger, "transport", "https"))
+		if err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
+		}
+		server.TLSConfig, err = appHttp.NewTLSConfig(certificates, config.TLSClientCAFile)
+		if err != nil {
+			level.Error(logger).Log("exit", err)
+			os.Exit(-1)
+		}
+	}
+
 	go func() {
+		if server.TLSConfig != nil {
+			level.Info(logger).Log("transport", "https", "address", httpAddr, "mtls", config.TLSClientCAFile != "", "msg", "listening")
+			errs <- server.ListenAndServeTLS("", "")
+			return
+		}
 		level.Info(logger).Log("transport", "http", "address", httpAddr, "msg", "listening")
-		errs <- http.ListenAndServe(httpAddr, nil)
+		errs <- server.ListenAndServe()
 	}()
 
 	// HANDLE OS FINISH SIGNAL
 	go func() {
-		c := make(chan os.Signal)
+		c := make(chan os.Signal, 1)
 		signal.Notify(c, syscall.SIGINT)
 		errs <- fmt.Errorf("%s", <-c)
 	}()
@@ -116,16 +318,52 @@ func main() {
 	level.Error(logger).Log("terminated", <-errs)
 }
 
-func accessControl(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PT, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
-
-		if r.Method == "OPTIONS" {
-			return
-		}
+// repositoryBackend returns the name of the repository used for the DB setting
+func repositoryBackend(db string) string {
+	if db == "mongo" {
+		return "mongo"
+	}
+	return "memory"
+}
 
-		h.ServeHTTP(w, r)
-	})
+// enabledFeatures lists the optional features turned on by the configuration
+func enabledFeatures(c config.Configuration) []string {
+	features := []string{}
+	if c.IdempotencyTTL > 0 {
+		features = append(features, "idempotency")
+	}
+	if c.MaxOrderTotal > 0 {
+		features = append(features, "max-order-total")
+	}
+	if c.SecurityToken != "" {
+		features = append(features, "admin")
+	}
+	if c.APIKeysRequired {
+		features = append(features, "api-keys")
+	}
+	if c.AuthorizationEnabled {
+		features = append(features, "authorization")
+	}
+	if len(c.RateLimits) > 0 {
+		features = append(features, "rate-limits")
+	}
+	if len(c.Bulkheads) > 0 {
+		features = append(features, "bulkheads")
+	}
+	if len(c.Timeouts) > 0 {
+		features = append(features, "timeouts")
+	}
+	if c.TLSCertFile != "" {
+		features = append(features, "tls")
+	}
+	if c.TLSClientCAFile != "" {
+		features = append(features, "mtls")
+	}
+	if c.CORSCredentials {
+		features = append(features, "cors-credentials")
+	}
+	if len(c.AccessLogSampling) > 0 {
+		features = append(features, "access-log-sampling")
+	}
+	return features
 }
@@ -0,0 +1,232 @@
+package codec
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+// CSVHeader lists the columns of the orders CSV, there is a row for each order item
+// repeating the order columns, or a single row without item columns for orders without items
+var CSVHeader = []string{
+	"id", "customer_id", "restaurant_id", "status", "version",
+	"created_on", "updated_on", "accepted_on", "preparing_on", "delivered_on", "cancelled_on", "rejected_on",
+	"product_code", "name", "unit_price", "quantity",
+}
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSVWriter returns an order writer of flattened item rows
+func NewCSVWriter(w io.Writer) OrderWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Write(order model.Order) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	columns := []string{
+		order.ID, order.CustomerID, order.RestaurantID, order.Status, formatInt(order.Version),
+		formatInt(order.CreatedOn), formatInt(order.UpdatedOn), formatInt(order.AcceptedOn),
+		formatInt(order.PreparingOn), formatInt(order.DeliveredOn), formatInt(order.CancelledOn),
+		formatInt(order.RejectedOn),
+	}
+	if len(order.OrderItems) == 0 {
+		return c.w.Write(append(columns, "", "", "", ""))
+	}
+	for _, item := range order.OrderItems {
+		row := append(columns[:len(columns):len(columns)],
+			item.ProductCode,
+			item.Name,
+			strconv.FormatFloat(float64(item.UnitPrice), 'f', -1, 32),
+			strconv.FormatInt(int64(item.Quantity), 10),
+		)
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Flush() error {
+	if !c.wroteHeader {
+		if err := c.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatInt leaves unset values empty
+func formatInt(value int64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+type csvRow struct {
+	line  int
+	order model.Order
+	item  *model.OrderItem
+	err   error
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	next    *csvRow
+}
+
+// NewCSVReader returns an order reader of flattened item rows, the columns are taken
+// from the header and the consecutive rows of the same id are merged in one order
+func NewCSVReader(r io.Reader) OrderReader {
+	return &csvReader{r: csv.NewReader(r)}
+}
+
+func (c *csvReader) Read() (Record, error) {
+	if c.columns == nil {
+		header, err := c.r.Read()
+		if err != nil {
+			return Record{}, err
+		}
+		c.columns = make(map[string]int, len(header))
+		for i, name := range header {
+			c.columns[strings.TrimSpace(name)] = i
+		}
+	}
+
+	current := c.next
+	c.next = nil
+	if current == nil {
+		row, err := c.readRow()
+		if err != nil {
+			return Record{}, err
+		}
+		current = row
+	}
+	record := Record{Line: current.line, Order: current.order, Err: current.err}
+	if current.item != nil {
+		record.Order.OrderItems = append(record.Order.OrderItems, *current.item)
+	}
+
+	for record.Order.ID != "" {
+		row, err := c.readRow()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Record{}, err
+		}
+		if row.order.ID != record.Order.ID {
+			c.next = row
+			break
+		}
+		if row.err != nil && record.Err == nil {
+			record.Err = row.err
+		}
+		if row.item != nil {
+			record.Order.OrderItems = append(record.Order.OrderItems, *row.item)
+		}
+	}
+	return record, nil
+}
+
+// readRow reads a row, parse errors of the row are kept on it to reject its order
+func (c *csvReader) readRow() (*csvRow, error) {
+	values, err := c.r.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	row := &csvRow{}
+	if parseErr, ok := err.(*csv.ParseError); ok {
+		row.line = parseErr.StartLine
+		row.err = parseErr
+		if values == nil {
+			return row, nil
+		}
+	} else if err != nil {
+		return nil, err
+	} else {
+		row.line, _ = c.r.FieldPos(0)
+	}
+
+	get := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+	order := model.Order{
+		ID:           get("id"),
+		CustomerID:   get("customer_id"),
+		RestaurantID: get("restaurant_id"),
+		Status:       get("status"),
+	}
+	ints := []struct {
+		name  string
+		value *int64
+	}{
+		{"version", &order.Version},
+		{"created_on", &order.CreatedOn},
+		{"updated_on", &order.UpdatedOn},
+		{"accepted_on", &order.AcceptedOn},
+		{"preparing_on", &order.PreparingOn},
+		{"delivered_on", &order.DeliveredOn},
+		{"cancelled_on", &order.CancelledOn},
+		{"rejected_on", &order.RejectedOn},
+	}
+	for _, field := range ints {
+		if err := parseInt(get(field.name), field.value); err != nil && row.err == nil {
+			row.err = fmt.Errorf("%s: %s", field.name, err)
+		}
+	}
+	row.order = order
+
+	code, name, price, quantity := get("product_code"), get("name"), get("unit_price"), get("quantity")
+	if code == "" && name == "" && price == "" && quantity == "" {
+		return row, nil
+	}
+	item := &model.OrderItem{ProductCode: code, Name: name}
+	if price != "" {
+		value, err := strconv.ParseFloat(price, 32)
+		if err != nil && row.err == nil {
+			row.err = fmt.Errorf("unit_price: %s", err)
+		}
+		item.UnitPrice = float32(value)
+	}
+	if quantity != "" {
+		value, err := strconv.ParseInt(quantity, 10, 32)
+		if err != nil && row.err == nil {
+			row.err = fmt.Errorf("quantity: %s", err)
+		}
+		item.Quantity = int32(value)
+	}
+	row.item = item
+	return row, nil
+}
+
+// parseInt leaves the value unset when empty
+func parseInt(text string, value *int64) error {
+	if text == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return err
+	}
+	*value = parsed
+	return nil
+}
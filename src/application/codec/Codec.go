@@ -0,0 +1,47 @@
+package codec
+
+import (
+	"errors"
+	"io"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+// Formats of the order files
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var (
+	// ErrUnknownFormat when the format is not supported
+	ErrUnknownFormat = errors.New("unknown format, use csv or ndjson")
+)
+
+// OrderWriter writes orders one by one in a file format
+type OrderWriter interface {
+	Write(order model.Order) error
+	Flush() error
+}
+
+// NewWriter returns the order writer of the format
+func NewWriter(format string, w io.Writer) (OrderWriter, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatNDJSON:
+		return NewNDJSONWriter(w), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns the media type of the format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,153 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+func TestOrderWriters(t *testing.T) {
+	order := model.Order{
+		ID:           "1",
+		RestaurantID: "EL MAGIO",
+		Status:       model.StatusPending,
+		CreatedOn:    100,
+		Version:      1,
+		OrderItems: []model.OrderItem{
+			{ProductCode: "P1", Name: "Pizza, large", UnitPrice: 10.5, Quantity: 2},
+			{ProductCode: "P2", Name: "Soda", UnitPrice: 1, Quantity: 1},
+		},
+	}
+
+	t.Run("csvWriter.Write",
+		func(t *testing.T) {
+			t.Run("WHEN an order has items SHOULD write a row for each item",
+				func(t *testing.T) {
+					var buf bytes.Buffer
+					writer := NewCSVWriter(&buf)
+					assert.NilError(t, writer.Write(order))
+					assert.NilError(t, writer.Write(model.Order{ID: "2"}))
+					assert.NilError(t, writer.Flush())
+
+					lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+					assert.Equal(t, len(lines), 4)
+					assert.Equal(t, lines[0], strings.Join(CSVHeader, ","))
+					assert.Equal(t, lines[1], `1,,EL MAGIO,Pending,1,100,,,,,,,P1,"Pizza, large",10.5,2`)
+					assert.Equal(t, lines[2], `1,,EL MAGIO,Pending,1,100,,,,,,,P2,Soda,1,1`)
+					assert.Equal(t, lines[3], `2,,,,,,,,,,,,,,,`)
+				})
+
+			t.Run("WHEN there are no orders SHOULD write only the header",
+				func(t *testing.T) {
+					var buf bytes.Buffer
+					writer := NewCSVWriter(&buf)
+					assert.NilError(t, writer.Flush())
+					assert.Equal(t, buf.String(), strings.Join(CSVHeader, ",")+"\n")
+				})
+		})
+
+	t.Run("ndjsonWriter.Write",
+		func(t *testing.T) {
+			t.Run("WHEN orders are written SHOULD write a JSON document per line",
+				func(t *testing.T) {
+					var buf bytes.Buffer
+					writer, err := NewWriter(FormatNDJSON, &buf)
+					assert.NilError(t, err)
+					assert.NilError(t, writer.Write(order))
+					assert.NilError(t, writer.Write(model.Order{ID: "2"}))
+					assert.NilError(t, writer.Flush())
+
+					lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+					assert.Equal(t, len(lines), 2)
+					assert.Assert(t, strings.HasPrefix(lines[1], `{"id":"2"`))
+				})
+		})
+
+	t.Run("NewWriter",
+		func(t *testing.T) {
+			t.Run("WHEN the format is unknown SHOULD return an error",
+				func(t *testing.T) {
+					_, err := NewWriter("xml", &bytes.Buffer{})
+					assert.Equal(t, err, ErrUnknownFormat)
+				})
+		})
+
+	t.Run("csvReader.Read",
+		func(t *testing.T) {
+			t.Run("WHEN the file was exported SHOULD read back the same orders",
+				func(t *testing.T) {
+					var buf bytes.Buffer
+					writer := NewCSVWriter(&buf)
+					assert.NilError(t, writer.Write(order))
+					assert.NilError(t, writer.Write(model.Order{ID: "2", RestaurantID: "EL MAGIO"}))
+					assert.NilError(t, writer.Flush())
+
+					reader := NewCSVReader(&buf)
+					first, err := reader.Read()
+					assert.NilError(t, err)
+					assert.NilError(t, first.Err)
+					assert.Equal(t, first.Line, 2)
+					assert.DeepEqual(t, first.Order, order)
+
+					second, err := reader.Read()
+					assert.NilError(t, err)
+					assert.Equal(t, second.Line, 4)
+					assert.DeepEqual(t, second.Order, model.Order{ID: "2", RestaurantID: "EL MAGIO"})
+
+					_, err = reader.Read()
+					assert.Equal(t, err, io.EOF)
+				})
+
+			t.Run("WHEN a row has invalid values SHOULD reject its order only",
+				func(t *testing.T) {
+					file := "id,restaurant_id,product_code,quantity\n" +
+						"1,A,P1,2\n" +
+						"1,A,P2,two\n" +
+						"2,A,P1,1\n"
+					reader := NewCSVReader(strings.NewReader(file))
+
+					first, err := reader.Read()
+					assert.NilError(t, err)
+					assert.ErrorContains(t, first.Err, "quantity")
+					assert.Equal(t, first.Order.ID, "1")
+
+					second, err := reader.Read()
+					assert.NilError(t, err)
+					assert.NilError(t, second.Err)
+					assert.Equal(t, second.Line, 4)
+					assert.Equal(t, second.Order.OrderItems[0].Quantity, int32(1))
+				})
+		})
+
+	t.Run("ndjsonReader.Read",
+		func(t *testing.T) {
+			t.Run("WHEN a line is not valid JSON SHOULD reject that line only",
+				func(t *testing.T) {
+					file := `{"id":"1","restaurant_id":"A"}` + "\n\n{oops\n" + `{"id":"3"}`
+					reader, err := NewReader(FormatNDJSON, strings.NewReader(file))
+					assert.NilError(t, err)
+
+					first, err := reader.Read()
+					assert.NilError(t, err)
+					assert.NilError(t, first.Err)
+					assert.Equal(t, first.Order.RestaurantID, "A")
+
+					second, err := reader.Read()
+					assert.NilError(t, err)
+					assert.Equal(t, second.Line, 3)
+					assert.Assert(t, second.Err != nil)
+
+					third, err := reader.Read()
+					assert.NilError(t, err)
+					assert.Equal(t, third.Order.ID, "3")
+
+					_, err = reader.Read()
+					assert.Equal(t, err, io.EOF)
+				})
+		})
+}
@@ -0,0 +1,61 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+// maxLineSize is the maximum size of an order document
+const maxLineSize = 1024 * 1024
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter returns an order writer of a JSON document per line
+func NewNDJSONWriter(w io.Writer) OrderWriter {
+	buffered := bufio.NewWriter(w)
+	return &ndjsonWriter{w: buffered, enc: json.NewEncoder(buffered)}
+}
+
+func (n *ndjsonWriter) Write(order model.Order) error {
+	return n.enc.Encode(order)
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return n.w.Flush()
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewNDJSONReader returns an order reader of a JSON document per line, empty lines are skipped
+func NewNDJSONReader(r io.Reader) OrderReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (n *ndjsonReader) Read() (Record, error) {
+	for n.scanner.Scan() {
+		n.line++
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		record := Record{Line: n.line}
+		record.Err = json.Unmarshal(line, &record.Order)
+		return record, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
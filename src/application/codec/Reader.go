@@ -0,0 +1,31 @@
+package codec
+
+import (
+	"io"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+// Record holds an order read from a file, or the error that made its rows invalid
+type Record struct {
+	// Line where the order starts on the file
+	Line  int
+	Order model.Order
+	Err   error
+}
+
+// OrderReader reads orders one by one from a file format, returning io.EOF at the end
+type OrderReader interface {
+	Read() (Record, error)
+}
+
+// NewReader returns the order reader of the format
+func NewReader(format string, r io.Reader) (OrderReader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r), nil
+	case FormatNDJSON:
+		return NewNDJSONReader(r), nil
+	}
+	return nil, ErrUnknownFormat
+}
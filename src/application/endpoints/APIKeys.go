@@ -0,0 +1,145 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+var (
+	// ErrAPIKeyMissing when the request has no API key nor other credential while they are required
+	ErrAPIKeyMissing = errors.New("auth: the API key is missing")
+	// ErrAPIKeyInvalid when the API key is unknown or has been revoked
+	ErrAPIKeyInvalid = errors.New("auth: the API key is invalid")
+	// ErrScopeMissing when the API key or the mutual TLS client has not the scope required by the endpoint
+	ErrScopeMissing = errors.New("the caller has not the scope required")
+)
+
+// APIKeyScopes are the scopes required by the Order endpoints
+var APIKeyScopes = map[string]string{
+	CreateName:           model.ScopeOrdersWrite,
+	CreateBatchName:      model.ScopeOrdersWrite,
+	ChangeStatusName:     model.ScopeOrdersWrite,
+	GetByIDName:          model.ScopeOrdersRead,
+	GetAllName:           model.ScopeOrdersRead,
+	ExportName:           model.ScopeOrdersRead,
+	GetHistoryName:       model.ScopeOrdersRead,
+	CountName:            model.ScopeOrdersRead,
+	ImportName:           model.ScopeOrdersAdmin,
+	ChangeStatusBulkName: model.ScopeOrdersAdmin,
+}
+
+// ContextWithAPIKey returns a context carrying the API key of the request
+func ContextWithAPIKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// APIKeyFromContext returns the API key of the request, empty when none was sent
+func APIKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(apiKeyContextKey).(string)
+	return key
+}
+
+// APIKeyIdentityFromContext returns the key authenticated by APIKeyMiddleware
+func APIKeyIdentityFromContext(ctx context.Context) (model.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyIdentityContextKey).(model.APIKey)
+	return key, ok
+}
+
+// ContextWithSubjectRecorder returns a context carrying the function told about the
+// authenticated subject of the request, as the access log of the transport
+func ContextWithSubjectRecorder(ctx context.Context, record func(subject string)) context.Context {
+	return context.WithValue(ctx, subjectRecorderContextKey, record)
+}
+
+func recordSubject(ctx context.Context, subject string) {
+	if record, ok := ctx.Value(subjectRecorderContextKey).(func(string)); ok {
+		record(subject)
+	}
+}
+
+// CredentialPolicy tells how APIKeyMiddleware treats the requests without API key
+type CredentialPolicy struct {
+	// Required rejects the requests without API key nor valid bearer token
+	Required bool
+	// Secret signs the bearer tokens accepted instead of an API key
+	Secret []byte
+	// ClientScopes are the scopes of the mutual TLS clients by the common name of their certificate,
+	// the clients not listed are not trusted as services
+	ClientScopes map[string][]string
+}
+
+// TrustedClientFromContext returns the mutual TLS client granted scopes by the CredentialPolicy
+func TrustedClientFromContext(ctx context.Context) (ClientIdentity, bool) {
+	identity, ok := ctx.Value(trustedClientContextKey).(ClientIdentity)
+	return identity, ok
+}
+
+// WithAPIKeys authenticates the API key of the requests to every Order endpoint, requiring
+// from the API key callers the scope of APIKeyScopes
+func WithAPIKeys(apiKeys service.IAPIKeyService, policy CredentialPolicy) Option {
+	return func(s *OrderEndpoints) {
+		for name, scope := range APIKeyScopes {
+			WithMiddleware(APIKeyMiddleware(apiKeys, scope, policy), name)(s)
+		}
+	}
+}
+
+// APIKeyMiddleware authenticates the API key of the request, accepting it when active and with
+// the scope. The requests without API key go on when they carry a bearer token, left to the
+// authorization, or come from a mutual TLS client with the scope in the policy; the others only
+// when the policy does not require credentials.
+func APIKeyMiddleware(apiKeys service.IAPIKeyService, scope string, policy CredentialPolicy) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			raw := APIKeyFromContext(ctx)
+			if raw == "" {
+				return withoutAPIKey(ctx, request, next, scope, policy)
+			}
+			key, err := apiKeys.Authenticate(ctx, raw)
+			if err == service.ErrAPIKeyRejected {
+				return nil, ErrAPIKeyInvalid
+			}
+			if err != nil {
+				return nil, err
+			}
+			recordSubject(ctx, "api-key:"+key.ID)
+			if !key.HasScope(scope) {
+				return nil, ErrScopeMissing
+			}
+			return next(context.WithValue(ctx, apiKeyIdentityContextKey, key), request)
+		}
+	}
+}
+
+// withoutAPIKey calls next for a request without API key allowed by the policy
+func withoutAPIKey(ctx context.Context, request interface{}, next endpoint.Endpoint, scope string,
+	policy CredentialPolicy) (interface{}, error) {
+	if token := TokenFromContext(ctx); token != "" {
+		if policy.Required {
+			claims, err := parseToken(policy.Secret, token)
+			if err != nil {
+				return nil, err
+			}
+			ctx = context.WithValue(ctx, claimsContextKey, claims)
+		}
+		return next(ctx, request)
+	}
+	if identity, ok := ClientIdentityFromContext(ctx); ok {
+		if scopes, ok := policy.ClientScopes[identity.CommonName]; ok {
+			recordSubject(ctx, "client:"+identity.CommonName)
+			if !model.HasScope(scopes, scope) {
+				return nil, ErrScopeMissing
+			}
+			return next(context.WithValue(ctx, trustedClientContextKey, identity), request)
+		}
+	}
+	if policy.Required {
+		return nil, ErrAPIKeyMissing
+	}
+	return next(ctx, request)
+}
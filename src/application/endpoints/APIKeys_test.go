@@ -0,0 +1,134 @@
+package endpoints
+
+import (
+	"context"
+	"testing"
+
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/service"
+	"microservice_gokit_base/src/mocks"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	apiKeys := mocks.NewMockIAPIKeyService(mockCtrl)
+	reader := model.APIKey{ID: "k1", Scopes: []string{model.ScopeOrdersRead}}
+	secret := "secret"
+	required := CredentialPolicy{Required: true, Secret: []byte(secret)}
+	next := func(ctx context.Context, request interface{}) (interface{}, error) {
+		key, _ := APIKeyIdentityFromContext(ctx)
+		return key.ID, nil
+	}
+
+	t.Run("WHEN the key has the scope SHOULD call the endpoint and record the subject", func(t *testing.T) {
+		apiKeys.EXPECT().Authenticate(gomock.Any(), "upk_1").Return(reader, nil)
+		var subject string
+		ctx := ContextWithSubjectRecorder(ContextWithAPIKey(context.TODO(), "upk_1"), func(s string) { subject = s })
+		response, err := APIKeyMiddleware(apiKeys, model.ScopeOrdersRead, required)(next)(ctx, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, response, "k1")
+		assert.Equal(t, subject, "api-key:k1")
+	})
+	t.Run("WHEN the key has not the scope SHOULD be forbidden", func(t *testing.T) {
+		apiKeys.EXPECT().Authenticate(gomock.Any(), "upk_1").Return(reader, nil)
+		ctx := ContextWithAPIKey(context.TODO(), "upk_1")
+		_, err := APIKeyMiddleware(apiKeys, model.ScopeOrdersWrite, required)(next)(ctx, nil)
+		assert.Equal(t, err, ErrScopeMissing)
+	})
+	t.Run("WHEN the key is missing or rejected SHOULD fail to authenticate", func(t *testing.T) {
+		_, err := APIKeyMiddleware(apiKeys, model.ScopeOrdersRead, required)(next)(context.TODO(), nil)
+		assert.Equal(t, err, ErrAPIKeyMissing)
+
+		apiKeys.EXPECT().Authenticate(gomock.Any(), "upk_2").Return(model.APIKey{}, service.ErrAPIKeyRejected)
+		_, err = APIKeyMiddleware(apiKeys, model.ScopeOrdersRead, required)(next)(ContextWithAPIKey(context.TODO(), "upk_2"), nil)
+		assert.Equal(t, err, ErrAPIKeyInvalid)
+	})
+	t.Run("WHEN keys are required and a valid token is sent instead SHOULD call the endpoint", func(t *testing.T) {
+		ctx := ContextWithToken(context.TODO(), signToken(t, secret, jwt.MapClaims{"sub": "ana", "role": "customer"}))
+		response, err := APIKeyMiddleware(apiKeys, model.ScopeOrdersWrite, required)(next)(ctx, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, response, "")
+
+		ctx = ContextWithToken(context.TODO(), signToken(t, "other", jwt.MapClaims{"sub": "ana"}))
+		_, err = APIKeyMiddleware(apiKeys, model.ScopeOrdersWrite, required)(next)(ctx, nil)
+		assert.Equal(t, err, ErrTokenInvalid)
+	})
+	t.Run("WHEN the mutual TLS client has scopes in the policy SHOULD check them", func(t *testing.T) {
+		policy := CredentialPolicy{Required: true, ClientScopes: map[string][]string{"pos-1": {model.ScopeOrdersRead}}}
+		trusted := func(ctx context.Context, request interface{}) (interface{}, error) {
+			identity, _ := TrustedClientFromContext(ctx)
+			return identity.CommonName, nil
+		}
+		ctx := ContextWithClientIdentity(context.TODO(), ClientIdentity{CommonName: "pos-1"})
+		response, err := APIKeyMiddleware(apiKeys, model.ScopeOrdersRead, policy)(trusted)(ctx, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, response, "pos-1")
+		_, err = APIKeyMiddleware(apiKeys, model.ScopeOrdersAdmin, policy)(trusted)(ctx, nil)
+		assert.Equal(t, err, ErrScopeMissing)
+
+		ctx = ContextWithClientIdentity(context.TODO(), ClientIdentity{CommonName: "unknown"})
+		_, err = APIKeyMiddleware(apiKeys, model.ScopeOrdersRead, policy)(trusted)(ctx, nil)
+		assert.Equal(t, err, ErrAPIKeyMissing)
+		response, err = APIKeyMiddleware(apiKeys, model.ScopeOrdersRead, CredentialPolicy{})(trusted)(ctx, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, response, "")
+	})
+	t.Run("WHEN keys are not required SHOULD let the requests without key go on", func(t *testing.T) {
+		response, err := APIKeyMiddleware(apiKeys, model.ScopeOrdersRead, CredentialPolicy{})(next)(context.TODO(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, response, "")
+	})
+	t.Run("WHEN the endpoints require keys SHOULD check the scope of each one", func(t *testing.T) {
+		apiKeys.EXPECT().Authenticate(gomock.Any(), "upk_1").Return(reader, nil).Times(2)
+		e := MakeOrderEndpoints(nil, WithAPIKeys(apiKeys, required))
+		ctx := ContextWithAPIKey(context.TODO(), "upk_1")
+		_, err := e.CreateEndpoint()(ctx, CreateRequest{})
+		assert.Equal(t, err, ErrScopeMissing)
+		_, err = e.ImportEndpoint()(ctx, ImportRequest{})
+		assert.Equal(t, err, ErrScopeMissing)
+	})
+	t.Run("WHEN the key has the admin scope SHOULD be granted the other ones", func(t *testing.T) {
+		admin := model.APIKey{Scopes: []string{model.ScopeOrdersAdmin}}
+		assert.Assert(t, admin.HasScope(model.ScopeOrdersRead))
+		assert.Assert(t, admin.HasScope(model.ScopeOrdersWrite))
+		assert.Assert(t, !reader.HasScope(model.ScopeOrdersAdmin))
+	})
+}
+
+func TestAPIKeysWithAuthorization(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	secret := "secret"
+	apiKeys := mocks.NewMockIAPIKeyService(mockCtrl)
+	svc := mocks.NewMockIOrderService(mockCtrl)
+	callerOf := func(ctx context.Context, id string) (model.Order, error) {
+		principal, _ := service.PrincipalFromContext(ctx)
+		return model.Order{ID: id, CustomerID: principal.Subject}, nil
+	}
+
+	for _, policy := range []CredentialPolicy{{Required: true, Secret: []byte(secret)}, {Secret: []byte(secret)}} {
+		e := MakeOrderEndpoints(svc, WithAPIKeys(apiKeys, policy), WithPrincipal([]byte(secret)))
+
+		t.Run("WHEN a JWT is sent without API key SHOULD reach the service as its caller", func(t *testing.T) {
+			svc.EXPECT().GetByID(gomock.Any(), "1").DoAndReturn(callerOf)
+			ctx := ContextWithToken(context.TODO(), signToken(t, secret, jwt.MapClaims{"sub": "ana", "role": "customer"}))
+			response, err := e.GetByIDEndpoint()(ctx, GetByIDRequest{ID: "1"})
+			assert.NilError(t, err)
+			assert.Equal(t, response.(GetByIDResponse).Order.CustomerID, "ana")
+		})
+		t.Run("WHEN an API key is sent SHOULD reach the service as a service caller", func(t *testing.T) {
+			apiKeys.EXPECT().Authenticate(gomock.Any(), "upk_1").Return(model.APIKey{ID: "k1", Scopes: []string{model.ScopeOrdersRead}}, nil)
+			svc.EXPECT().GetByID(gomock.Any(), "1").DoAndReturn(callerOf)
+			response, err := e.GetByIDEndpoint()(ContextWithAPIKey(context.TODO(), "upk_1"), GetByIDRequest{ID: "1"})
+			assert.NilError(t, err)
+			assert.Equal(t, response.(GetByIDResponse).Order.CustomerID, "api-key:k1")
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+
+	"microservice_gokit_base/src/domain/service"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// ILogLevels describes the log levels of the components changed by the admin endpoints
+type ILogLevels interface {
+	Levels() map[string]string
+	SetLevel(component string, level string) error
+}
+
+// IAdminEndpoints holds the Go kit endpoints administering the service.
+type IAdminEndpoints interface {
+	GetLogLevelsEndpoint() endpoint.Endpoint
+	SetLogLevelEndpoint() endpoint.Endpoint
+	IssueAPIKeyEndpoint() endpoint.Endpoint
+	RevokeAPIKeyEndpoint() endpoint.Endpoint
+	GetVarsEndpoint() endpoint.Endpoint
+}
+
+// InvalidLogLevelError when the component or the level of a change are unknown
+type InvalidLogLevelError struct {
+	Err error
+}
+
+func (e InvalidLogLevelError) Error() string { return e.Err.Error() }
+
+// AdminEndpoints Struct to instanciate the admin endpoints
+type AdminEndpoints struct {
+	levels  ILogLevels
+	apiKeys service.IAPIKeyService
+	auth    endpoint.Middleware
+}
+
+// MakeAdminEndpoints initializes the admin endpoints, only reachable with an admin token signed by secret.
+func MakeAdminEndpoints(levels ILogLevels, apiKeys service.IAPIKeyService, secret []byte) IAdminEndpoints {
+	return &AdminEndpoints{
+		levels:  levels,
+		apiKeys: apiKeys,
+		auth:    JWTMiddleware(secret, AdminRole),
+	}
+}
+
+// GetLogLevelsRequest holds the request parameters for the GetLogLevels method.
+type GetLogLevelsRequest struct{}
+
+// LogLevelsResponse holds the response values for the log level methods.
+type LogLevelsResponse struct {
+	Levels map[string]string `json:"levels"`
+	Err    error             `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r LogLevelsResponse) Failed() error { return r.Err }
+
+// GetLogLevelsEndpoint returns the log level of every component
+func (s *AdminEndpoints) GetLogLevelsEndpoint() endpoint.Endpoint {
+	return s.auth(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return LogLevelsResponse{Levels: s.levels.Levels()}, nil
+	})
+}
+
+// SetLogLevelRequest holds the request parameters for the SetLogLevel method.
+type SetLogLevelRequest struct {
+	Component string `json:"component"`
+	Level     string `json:"level"`
+}
+
+// SetLogLevelEndpoint changes the log level of a component
+func (s *AdminEndpoints) SetLogLevelEndpoint() endpoint.Endpoint {
+	return s.auth(func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SetLogLevelRequest)
+		if err := s.levels.SetLevel(req.Component, req.Level); err != nil {
+			return LogLevelsResponse{Err: InvalidLogLevelError{Err: err}}, nil
+		}
+		return LogLevelsResponse{Levels: s.levels.Levels()}, nil
+	})
+}
+
+// IssueAPIKeyRequest holds the request parameters for the IssueAPIKey method.
+type IssueAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// IssueAPIKeyResponse holds the response values for the IssueAPIKey method,
+// the key is only returned once
+type IssueAPIKeyResponse struct {
+	ID        string   `json:"id,omitempty"`
+	Key       string   `json:"key,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+	CreatedAt int64    `json:"created_at,omitempty"`
+	Err       error    `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r IssueAPIKeyResponse) Failed() error { return r.Err }
+
+// IssueAPIKeyEndpoint creates an API key with the scopes
+func (s *AdminEndpoints) IssueAPIKeyEndpoint() endpoint.Endpoint {
+	return s.auth(func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(IssueAPIKeyRequest)
+		key, plain, err := s.apiKeys.Issue(ctx, req.Name, req.Scopes)
+		if err != nil {
+			return IssueAPIKeyResponse{Err: err}, nil
+		}
+		return IssueAPIKeyResponse{
+			ID:        key.ID,
+			Key:       plain,
+			Name:      key.Name,
+			Scopes:    key.Scopes,
+			CreatedAt: key.CreatedAt,
+		}, nil
+	})
+}
+
+// RevokeAPIKeyRequest holds the request parameters for the RevokeAPIKey method.
+type RevokeAPIKeyRequest struct {
+	ID string
+}
+
+// RevokeAPIKeyResponse holds the response values for the RevokeAPIKey method.
+type RevokeAPIKeyResponse struct {
+	Err error `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r RevokeAPIKeyResponse) Failed() error { return r.Err }
+
+// RevokeAPIKeyEndpoint stops accepting an API key
+func (s *AdminEndpoints) RevokeAPIKeyEndpoint() endpoint.Endpoint {
+	return s.auth(func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RevokeAPIKeyRequest)
+		return RevokeAPIKeyResponse{Err: s.apiKeys.Revoke(ctx, req.ID)}, nil
+	})
+}
+
+// hiddenVars are the published variables left out of GetVars, the command line may carry secrets
+var hiddenVars = map[string]bool{"cmdline": true}
+
+// GetVarsRequest holds the request parameters for the GetVars method.
+type GetVarsRequest struct{}
+
+// VarsResponse holds the response values for the GetVars method.
+type VarsResponse struct {
+	Vars map[string]interface{} `json:"vars"`
+}
+
+// GetVarsEndpoint returns the variables published with expvar, as the memory stats and the
+// counters of the service
+func (s *AdminEndpoints) GetVarsEndpoint() endpoint.Endpoint {
+	return s.auth(func(ctx context.Context, request interface{}) (interface{}, error) {
+		vars := map[string]interface{}{}
+		expvar.Do(func(kv expvar.KeyValue) {
+			if !hiddenVars[kv.Key] {
+				vars[kv.Key] = json.RawMessage(kv.Value.String())
+			}
+		})
+		return VarsResponse{Vars: vars}, nil
+	})
+}
+
+var (
+	_ endpoint.Failer = LogLevelsResponse{}
+	_ endpoint.Failer = IssueAPIKeyResponse{}
+	_ endpoint.Failer = RevokeAPIKeyResponse{}
+)
@@ -0,0 +1,86 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"gotest.tools/assert"
+)
+
+type logLevelsStub map[string]string
+
+func (l logLevelsStub) Levels() map[string]string { return l }
+
+func (l logLevelsStub) SetLevel(component string, level string) error {
+	if _, ok := l[component]; !ok {
+		return errors.New("the component has no log level")
+	}
+	l[component] = level
+	return nil
+}
+
+func signToken(t *testing.T, secret string, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	assert.NilError(t, err)
+	return token
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	secret := "secret"
+	admin := signToken(t, secret, jwt.MapClaims{"sub": "ops", "role": AdminRole})
+
+	t.Run("WHEN the token is an admin one SHOULD change the level", func(t *testing.T) {
+		levels := logLevelsStub{"service": "info"}
+		e := MakeAdminEndpoints(levels, nil, []byte(secret))
+		ctx := ContextWithToken(context.TODO(), admin)
+		response, err := e.SetLogLevelEndpoint()(ctx, SetLogLevelRequest{Component: "service", Level: "debug"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, response, LogLevelsResponse{Levels: map[string]string{"service": "debug"}})
+	})
+	t.Run("WHEN the component is unknown SHOULD fail with an invalid log level error", func(t *testing.T) {
+		e := MakeAdminEndpoints(logLevelsStub{}, nil, []byte(secret))
+		ctx := ContextWithToken(context.TODO(), admin)
+		response, err := e.SetLogLevelEndpoint()(ctx, SetLogLevelRequest{Component: "other", Level: "debug"})
+		assert.NilError(t, err)
+		_, ok := response.(LogLevelsResponse).Failed().(InvalidLogLevelError)
+		assert.Assert(t, ok)
+	})
+	t.Run("WHEN the token is missing, invalid or without the role SHOULD be rejected", func(t *testing.T) {
+		e := MakeAdminEndpoints(logLevelsStub{}, nil, []byte(secret)).GetLogLevelsEndpoint()
+		cases := map[string]error{
+			"": ErrTokenMissing,
+			signToken(t, "other", jwt.MapClaims{"role": AdminRole}):                                          ErrTokenInvalid,
+			signToken(t, secret, jwt.MapClaims{"role": AdminRole, "exp": time.Now().Add(-time.Hour).Unix()}): ErrTokenInvalid,
+			signToken(t, secret, jwt.MapClaims{"roles": []string{"viewer"}}):                                 ErrForbidden,
+		}
+		for token, expected := range cases {
+			_, err := e(ContextWithToken(context.TODO(), token), GetLogLevelsRequest{})
+			assert.Equal(t, err, expected)
+		}
+	})
+	t.Run("WHEN the token is an admin one SHOULD return the published variables but the command line", func(t *testing.T) {
+		expvar.NewInt("admin_test_requests").Set(3)
+		e := MakeAdminEndpoints(logLevelsStub{}, nil, []byte(secret)).GetVarsEndpoint()
+		response, err := e(ContextWithToken(context.TODO(), admin), GetVarsRequest{})
+		assert.NilError(t, err)
+		vars := response.(VarsResponse).Vars
+		assert.DeepEqual(t, vars["admin_test_requests"], json.RawMessage("3"))
+		_, ok := vars["memstats"]
+		assert.Assert(t, ok)
+		_, ok = vars["cmdline"]
+		assert.Assert(t, !ok)
+
+		_, err = e(context.TODO(), GetVarsRequest{})
+		assert.Equal(t, err, ErrTokenMissing)
+	})
+	t.Run("WHEN the secret is empty SHOULD reject every token", func(t *testing.T) {
+		e := MakeAdminEndpoints(logLevelsStub{}, nil, nil).GetLogLevelsEndpoint()
+		_, err := e(ContextWithToken(context.TODO(), admin), GetLogLevelsRequest{})
+		assert.Equal(t, err, ErrTokenInvalid)
+	})
+}
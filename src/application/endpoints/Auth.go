@@ -0,0 +1,131 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+
+	"microservice_gokit_base/src/domain/service"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// AdminRole is the role claim required by the admin endpoints
+const AdminRole = "admin"
+
+var (
+	// ErrTokenMissing when the request has no bearer token
+	ErrTokenMissing = errors.New("auth: the bearer token is missing")
+	// ErrTokenInvalid when the token is not signed with the secret or has expired
+	ErrTokenInvalid = errors.New("auth: the token is invalid")
+	// ErrForbidden when the token has not the role required by the endpoint
+	ErrForbidden = errors.New("the token has not the role required")
+)
+
+// ContextWithToken returns a context carrying the bearer token of the request
+func ContextWithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey, token)
+}
+
+// TokenFromContext returns the bearer token of the request, empty when none was sent
+func TokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenContextKey).(string)
+	return token
+}
+
+// ClaimsFromContext returns the claims of the token validated by JWTMiddleware
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(jwt.MapClaims)
+	return claims, ok
+}
+
+// callerFromContext returns the caller authenticated by the API key, the bearer token or the
+// trusted client certificate of the request, empty when none was
+func callerFromContext(ctx context.Context) string {
+	if key, ok := APIKeyIdentityFromContext(ctx); ok {
+		return "api-key:" + key.ID
+	}
+	if principal, ok := service.PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		return "sub:" + principal.Subject
+	}
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		if sub, _ := claims["sub"].(string); sub != "" {
+			return "sub:" + sub
+		}
+	}
+	if identity, ok := TrustedClientFromContext(ctx); ok {
+		return "cn:" + identity.CommonName
+	}
+	return ""
+}
+
+// ClientIdentity is the subject of the verified certificate of a mutual TLS client
+type ClientIdentity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	SerialNumber string
+}
+
+// ContextWithClientIdentity returns a context carrying the identity of the mutual TLS client
+func ContextWithClientIdentity(ctx context.Context, identity ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityContextKey, identity)
+}
+
+// ClientIdentityFromContext returns the identity of the mutual TLS client of the request
+func ClientIdentityFromContext(ctx context.Context) (ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityContextKey).(ClientIdentity)
+	return identity, ok
+}
+
+// JWTMiddleware accepts the requests whose token is signed by the secret with HS256
+// and has the role in its "role" or "roles" claim. An empty secret rejects every request.
+func JWTMiddleware(secret []byte, role string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			raw := TokenFromContext(ctx)
+			if raw == "" {
+				return nil, ErrTokenMissing
+			}
+			claims, err := parseToken(secret, raw)
+			if err != nil {
+				return nil, err
+			}
+			if !hasRole(claims, role) {
+				return nil, ErrForbidden
+			}
+			return next(context.WithValue(ctx, claimsContextKey, claims), request)
+		}
+	}
+}
+
+// parseToken returns the claims of a token signed by the secret with HS256, an empty secret rejects every token
+func parseToken(secret []byte, raw string) (jwt.MapClaims, error) {
+	if len(secret) == 0 {
+		return nil, ErrTokenInvalid
+	}
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, ErrTokenInvalid
+		}
+		return secret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+func hasRole(claims jwt.MapClaims, role string) bool {
+	if r, ok := claims["role"].(string); ok && r == role {
+		return true
+	}
+	roles, _ := claims["roles"].([]interface{})
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
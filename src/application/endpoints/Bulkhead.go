@@ -0,0 +1,167 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+)
+
+var (
+	// ErrShed when the endpoint has too many requests in flight and queued
+	ErrShed = errors.New("the service is overloaded, retry later")
+	// ErrTimeout when the request has not been handled before the deadline of the endpoint
+	ErrTimeout = errors.New("the request was not handled in time")
+	// ErrInvalidBulkhead when a bulkhead is not written as "<in flight>:<queue>:<queue timeout>"
+	ErrInvalidBulkhead = errors.New(`the bulkhead must be like "20:50:2s", in flight, queued and queue timeout`)
+)
+
+// streamingEndpoints write their response after returning, so a deadline would cut it
+var streamingEndpoints = map[string]bool{ExportName: true}
+
+// streamingResponse is the response of a streaming endpoint, calling release once the
+// transport has written it
+type streamingResponse interface {
+	releaseOnClose(release func()) interface{}
+}
+
+// Bulkhead limits the requests handled at once by an endpoint, the requests over
+// MaxInFlight wait up to QueueTimeout while there are less than MaxQueue waiting
+type Bulkhead struct {
+	MaxInFlight  int
+	MaxQueue     int
+	QueueTimeout time.Duration
+}
+
+// ParseBulkhead reads a bulkhead like "20:50:2s"
+func ParseBulkhead(s string) (Bulkhead, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return Bulkhead{}, ErrInvalidBulkhead
+	}
+	inFlight, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || inFlight < 1 {
+		return Bulkhead{}, ErrInvalidBulkhead
+	}
+	queue, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || queue < 0 {
+		return Bulkhead{}, ErrInvalidBulkhead
+	}
+	timeout, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+	if err != nil || timeout < 0 {
+		return Bulkhead{}, ErrInvalidBulkhead
+	}
+	return Bulkhead{MaxInFlight: inFlight, MaxQueue: queue, QueueTimeout: timeout}, nil
+}
+
+// BulkheadMetrics are the metrics of the bulkhead of an endpoint
+type BulkheadMetrics struct {
+	InFlight metrics.Gauge
+	Queued   metrics.Gauge
+	Shed     metrics.Counter
+}
+
+// WithBulkheads limits the concurrency of the named endpoints, newMetrics returns the metrics of each one
+func WithBulkheads(bulkheads map[string]Bulkhead, newMetrics func(name string) BulkheadMetrics) Option {
+	return func(s *OrderEndpoints) {
+		for name, bulkhead := range bulkheads {
+			WithMiddleware(BulkheadMiddleware(bulkhead, newMetrics(name)), name)(s)
+		}
+	}
+}
+
+// BulkheadMiddleware handles at most MaxInFlight requests at once, queueing the next ones
+// and failing with ErrShed the ones over the queue or waiting longer than its timeout.
+// The streamed responses hold their slot until the transport has written them.
+func BulkheadMiddleware(bulkhead Bulkhead, m BulkheadMetrics) endpoint.Middleware {
+	slots := make(chan struct{}, bulkhead.MaxInFlight)
+	var queued int64
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				if atomic.AddInt64(&queued, 1) > int64(bulkhead.MaxQueue) {
+					atomic.AddInt64(&queued, -1)
+					m.Shed.Add(1)
+					return nil, ErrShed
+				}
+				m.Queued.Add(1)
+				timer := time.NewTimer(bulkhead.QueueTimeout)
+				var err error
+				select {
+				case slots <- struct{}{}:
+				case <-timer.C:
+					err = ErrShed
+				case <-ctx.Done():
+					err = ctx.Err()
+				}
+				timer.Stop()
+				atomic.AddInt64(&queued, -1)
+				m.Queued.Add(-1)
+				if err != nil {
+					if err == ErrShed {
+						m.Shed.Add(1)
+					}
+					return nil, err
+				}
+			}
+			m.InFlight.Add(1)
+			var once sync.Once
+			release := func() {
+				once.Do(func() {
+					<-slots
+					m.InFlight.Add(-1)
+				})
+			}
+			streaming := false
+			defer func() {
+				if !streaming {
+					release()
+				}
+			}()
+			response, err := next(ctx, request)
+			if r, ok := response.(streamingResponse); ok && err == nil {
+				streaming = true
+				return r.releaseOnClose(release), nil
+			}
+			return response, err
+		}
+	}
+}
+
+// WithTimeouts sets the deadline of the named endpoints, the streaming ones are left without
+func WithTimeouts(timeouts map[string]time.Duration) Option {
+	return func(s *OrderEndpoints) {
+		for name, timeout := range timeouts {
+			if !streamingEndpoints[name] {
+				WithMiddleware(TimeoutMiddleware(timeout), name)(s)
+			}
+		}
+	}
+}
+
+// TimeoutMiddleware cancels the context of the request after the timeout, so the repository
+// calls are cancelled too, failing with ErrTimeout when the request failed past the deadline.
+// The requests done by the deadline keep their response, as a write already committed.
+func TimeoutMiddleware(timeout time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			response, err := next(ctx, request)
+			f, ok := response.(endpoint.Failer)
+			failed := err != nil || (ok && f.Failed() != nil)
+			if failed && ctx.Err() == context.DeadlineExceeded {
+				return nil, ErrTimeout
+			}
+			return response, err
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package endpoints
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/mocks"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+// stubIterator is an iterator without orders
+type stubIterator struct{}
+
+func (*stubIterator) Next(ctx context.Context) bool   { return false }
+func (*stubIterator) Decode(order *model.Order) error { return nil }
+func (*stubIterator) Err() error                      { return nil }
+func (*stubIterator) Close(ctx context.Context) error { return nil }
+
+func TestBulkheadMiddleware(t *testing.T) {
+	newMetrics := func() BulkheadMetrics {
+		return BulkheadMetrics{
+			InFlight: generic.NewGauge("in_flight"),
+			Queued:   generic.NewGauge("queued"),
+			Shed:     generic.NewCounter("shed"),
+		}
+	}
+	// blocking holds the requests until release is closed, telling when each one starts
+	blocking := func(started chan<- struct{}, release <-chan struct{}) func(ctx context.Context, request interface{}) (interface{}, error) {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-release
+			return "ok", nil
+		}
+	}
+
+	t.Run("WHEN the queue is full SHOULD shed the request", func(t *testing.T) {
+		m := newMetrics()
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		e := BulkheadMiddleware(Bulkhead{MaxInFlight: 1, MaxQueue: 0, QueueTimeout: time.Second}, m)(blocking(started, release))
+		go e(context.TODO(), nil)
+		<-started
+		assert.Equal(t, m.InFlight.(*generic.Gauge).Value(), float64(1))
+
+		_, err := e(context.TODO(), nil)
+		assert.Equal(t, err, ErrShed)
+		assert.Equal(t, m.Shed.(*generic.Counter).Value(), float64(1))
+		close(release)
+	})
+	t.Run("WHEN a slot is freed before the queue timeout SHOULD handle the queued request", func(t *testing.T) {
+		m := newMetrics()
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		e := BulkheadMiddleware(Bulkhead{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute}, m)(blocking(started, release))
+		go e(context.TODO(), nil)
+		<-started
+
+		done := make(chan error)
+		go func() {
+			_, err := e(context.TODO(), nil)
+			done <- err
+		}()
+		for m.Queued.(*generic.Gauge).Value() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+		assert.NilError(t, <-done)
+		assert.Equal(t, m.Queued.(*generic.Gauge).Value(), float64(0))
+		assert.Equal(t, m.Shed.(*generic.Counter).Value(), float64(0))
+	})
+	t.Run("WHEN the queued request waits longer than the timeout SHOULD shed it", func(t *testing.T) {
+		m := newMetrics()
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		e := BulkheadMiddleware(Bulkhead{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond}, m)(blocking(started, release))
+		go e(context.TODO(), nil)
+		<-started
+
+		_, err := e(context.TODO(), nil)
+		assert.Equal(t, err, ErrShed)
+		assert.Equal(t, m.Shed.(*generic.Counter).Value(), float64(1))
+		close(release)
+	})
+	t.Run("WHEN the deadline wraps the bulkhead SHOULD count the time queued", func(t *testing.T) {
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		bulkhead := BulkheadMiddleware(Bulkhead{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute}, newMetrics())
+		e := TimeoutMiddleware(10 * time.Millisecond)(bulkhead(blocking(started, release)))
+		go e(context.TODO(), nil)
+		<-started
+
+		_, err := e(context.TODO(), nil)
+		assert.Equal(t, err, ErrTimeout)
+		close(release)
+	})
+	t.Run("WHEN the export is streamed SHOULD hold its slot until the orders are closed", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		svc := mocks.NewMockIOrderService(mockCtrl)
+		svc.EXPECT().Export(gomock.Any(), gomock.Any()).Return(&stubIterator{}, nil).Times(2)
+		m := newMetrics()
+		e := MakeOrderEndpoints(svc, WithBulkheads(map[string]Bulkhead{ExportName: {MaxInFlight: 1, QueueTimeout: time.Millisecond}},
+			func(string) BulkheadMetrics { return m })).ExportEndpoint()
+
+		first, err := e(context.TODO(), ExportRequest{Format: "csv"})
+		assert.NilError(t, err)
+		assert.Equal(t, m.InFlight.(*generic.Gauge).Value(), float64(1))
+		_, err = e(context.TODO(), ExportRequest{Format: "csv"})
+		assert.Equal(t, err, ErrShed)
+
+		assert.NilError(t, first.(ExportResponse).Orders.Close(context.TODO()))
+		assert.Equal(t, m.InFlight.(*generic.Gauge).Value(), float64(0))
+		_, err = e(context.TODO(), ExportRequest{Format: "csv"})
+		assert.NilError(t, err)
+	})
+	t.Run("WHEN the bulkhead is parsed SHOULD read its limits", func(t *testing.T) {
+		bulkhead, err := ParseBulkhead("20:50:2s")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, bulkhead, Bulkhead{MaxInFlight: 20, MaxQueue: 50, QueueTimeout: 2 * time.Second})
+		for _, spec := range []string{"20", "0:1:1s", "1:-1:1s", "1:1:x"} {
+			_, err := ParseBulkhead(spec)
+			assert.Equal(t, err, ErrInvalidBulkhead, spec)
+		}
+	})
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	t.Run("WHEN the deadline is exceeded SHOULD cancel the context and fail with a timeout", func(t *testing.T) {
+		e := TimeoutMiddleware(10 * time.Millisecond)(func(ctx context.Context, request interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return GetByIDResponse{Err: ctx.Err()}, nil
+		})
+		_, err := e(context.TODO(), nil)
+		assert.Equal(t, err, ErrTimeout)
+	})
+	t.Run("WHEN the request completes right at the deadline SHOULD keep its response", func(t *testing.T) {
+		e := TimeoutMiddleware(10 * time.Millisecond)(func(ctx context.Context, request interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return CreateResponse{ID: "1"}, nil
+		})
+		response, err := e(context.TODO(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, response.(CreateResponse).ID, "1")
+	})
+	t.Run("WHEN the endpoint streams its response SHOULD not set a deadline", func(t *testing.T) {
+		e := MakeOrderEndpoints(nil, WithTimeouts(map[string]time.Duration{ExportName: time.Nanosecond}))
+		assert.Equal(t, len(e.(*OrderEndpoints).middlewares), 0)
+	})
+}
@@ -0,0 +1,160 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// idempotencyBookkeepingTimeout bounds the completion or release of a key, done after the
+// request so they are not cancelled with it
+const idempotencyBookkeepingTimeout = 5 * time.Second
+
+var (
+	// ErrIdempotencyKeyReused when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used with a different request")
+	// ErrIdempotencyInProgress when the request of a key is still being processed
+	ErrIdempotencyInProgress = errors.New("a request with the same idempotency key is in progress")
+)
+
+type contextKey int
+
+const (
+	idempotencyKeyContextKey contextKey = iota
+	tokenContextKey
+	claimsContextKey
+	clientIdentityContextKey
+	apiKeyContextKey
+	apiKeyIdentityContextKey
+	subjectRecorderContextKey
+	clientIPContextKey
+	rateLimitRecorderContextKey
+	trustedClientContextKey
+)
+
+// ContextWithIdempotencyKey returns a context carrying the idempotency key of the request
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the request, empty when none was sent
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey).(string)
+	return key
+}
+
+// WithIdempotency makes the Create endpoint replay its response for retried requests
+func WithIdempotency(repo domainRepo.IIdempotencyRepository, ttl time.Duration, logger log.Logger) Option {
+	return WithMiddleware(IdempotencyMiddleware(repo, ttl, decodeCreateResponse, logger), CreateName)
+}
+
+// IdempotencyMiddleware stores the response of the requests sent with an idempotency key
+// and replays it when the same request is sent again with the key by the same caller before
+// it expires, the keys of each authenticated caller are apart from the others'.
+// Failed responses are not stored so the client can retry them. The key is completed or
+// released even when the request was cancelled or ran out of time, otherwise the retries
+// would find it in progress until it expires.
+func IdempotencyMiddleware(repo domainRepo.IIdempotencyRepository, ttl time.Duration,
+	decode func([]byte) (interface{}, error), logger log.Logger) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			key := IdempotencyKeyFromContext(ctx)
+			if key == "" {
+				return next(ctx, request)
+			}
+			key = idempotencyNamespace(ctx) + "/" + key
+			hash, err := requestHash(request)
+			if err != nil {
+				return nil, err
+			}
+			record, reserved, err := repo.Reserve(ctx, model.IdempotencyRecord{
+				Key:         key,
+				RequestHash: hash,
+				ExpiresAt:   time.Now().Add(ttl),
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !reserved {
+				if record.RequestHash != hash {
+					return nil, ErrIdempotencyKeyReused
+				}
+				if !record.Completed {
+					return nil, ErrIdempotencyInProgress
+				}
+				return decode(record.Response)
+			}
+
+			response, err := next(ctx, request)
+			bookkeeping, cancel := context.WithTimeout(detachedContext{ctx}, idempotencyBookkeepingTimeout)
+			defer cancel()
+			logger := utils.LoggerFromContext(ctx, logger)
+			if f, ok := response.(endpoint.Failer); err != nil || (ok && f.Failed() != nil) {
+				releaseIdempotencyKey(bookkeeping, repo, key, logger)
+				return response, err
+			}
+			stored, err := json.Marshal(response)
+			if err != nil {
+				level.Error(logger).Log("msg", "unable to store the idempotent response", "err", err)
+				releaseIdempotencyKey(bookkeeping, repo, key, logger)
+				return response, nil
+			}
+			if err := repo.Complete(bookkeeping, key, stored); err != nil {
+				level.Error(logger).Log("msg", "unable to complete the idempotency key", "err", err)
+				releaseIdempotencyKey(bookkeeping, repo, key, logger)
+			}
+			return response, nil
+		}
+	}
+}
+
+func releaseIdempotencyKey(ctx context.Context, repo domainRepo.IIdempotencyRepository, key string, logger log.Logger) {
+	if err := repo.Release(ctx, key); err != nil {
+		level.Error(logger).Log("msg", "unable to release the idempotency key", "err", err)
+	}
+}
+
+// detachedContext keeps the values of the request context, as its request id, without
+// its deadline and cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// idempotencyNamespace returns the caller the keys belong to, the requests without
+// credentials share one namespace
+func idempotencyNamespace(ctx context.Context) string {
+	if caller := callerFromContext(ctx); caller != "" {
+		return caller
+	}
+	return "anonymous"
+}
+
+func requestHash(request interface{}) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func decodeCreateResponse(stored []byte) (interface{}, error) {
+	var response CreateResponse
+	err := json.Unmarshal(stored, &response)
+	return response, err
+}
@@ -0,0 +1,133 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"gotest.tools/assert"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/domain/service"
+	"microservice_gokit_base/src/infraestructure/repository"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	logger := log.NewLogfmtLogger(os.Stderr)
+	repo, err := repository.NewIdempotencyRepositoryMem(logger)
+	assert.NilError(t, err)
+
+	var (
+		calls    int
+		failNext bool
+		next     = func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls++
+			if failNext {
+				return CreateResponse{Err: errors.New("errors")}, nil
+			}
+			return CreateResponse{ID: request.(CreateRequest).Order.RestaurantID}, nil
+		}
+		create = IdempotencyMiddleware(contextRepo{repo}, time.Minute, decodeCreateResponse, logger)(next)
+		req    = CreateRequest{Order: model.Order{RestaurantID: "001"}}
+	)
+
+	t.Run("WHEN no key is sent SHOULD always call the endpoint",
+		func(t *testing.T) {
+			calls = 0
+			_, err := create(context.TODO(), req)
+			assert.NilError(t, err)
+			_, err = create(context.TODO(), req)
+			assert.NilError(t, err)
+			assert.Equal(t, calls, 2)
+		})
+
+	t.Run("WHEN the same request is retried with a key SHOULD replay the response",
+		func(t *testing.T) {
+			calls = 0
+			ctx := ContextWithIdempotencyKey(context.TODO(), "key-1")
+			first, err := create(ctx, req)
+			assert.NilError(t, err)
+			second, err := create(ctx, req)
+			assert.NilError(t, err)
+			assert.Equal(t, calls, 1)
+			assert.Equal(t, second.(CreateResponse).ID, first.(CreateResponse).ID)
+		})
+
+	t.Run("WHEN the key is reused with a different request SHOULD return an error",
+		func(t *testing.T) {
+			ctx := ContextWithIdempotencyKey(context.TODO(), "key-1")
+			other := CreateRequest{Order: model.Order{RestaurantID: "002"}}
+			_, err := create(ctx, other)
+			assert.Equal(t, err, ErrIdempotencyKeyReused)
+		})
+
+	t.Run("WHEN the response failed SHOULD let the client retry",
+		func(t *testing.T) {
+			calls = 0
+			ctx := ContextWithIdempotencyKey(context.TODO(), "key-2")
+			failNext = true
+			res, err := create(ctx, req)
+			assert.NilError(t, err)
+			assert.Assert(t, res.(CreateResponse).Err != nil)
+
+			failNext = false
+			res, err = create(ctx, req)
+			assert.NilError(t, err)
+			assert.Equal(t, res.(CreateResponse).ID, "001")
+			assert.Equal(t, calls, 2)
+		})
+
+	t.Run("WHEN another caller sends the same key and request SHOULD not get the response of the first",
+		func(t *testing.T) {
+			calls = 0
+			ctx := ContextWithIdempotencyKey(context.TODO(), "key-4")
+			_, err := create(service.ContextWithPrincipal(ctx, model.Principal{Subject: "alice"}), req)
+			assert.NilError(t, err)
+			_, err = create(service.ContextWithPrincipal(ctx, model.Principal{Subject: "bob"}), req)
+			assert.NilError(t, err)
+			_, err = create(service.ContextWithPrincipal(ctx, model.Principal{Subject: "alice"}), req)
+			assert.NilError(t, err)
+			assert.Equal(t, calls, 2)
+		})
+
+	t.Run("WHEN the request is cancelled after creating SHOULD still replay the response",
+		func(t *testing.T) {
+			calls = 0
+			ctx, cancel := context.WithCancel(ContextWithIdempotencyKey(context.TODO(), "key-3"))
+			cancelled := IdempotencyMiddleware(contextRepo{repo}, time.Minute, decodeCreateResponse, logger)(
+				func(ctx context.Context, request interface{}) (interface{}, error) {
+					cancel()
+					return next(ctx, request)
+				})
+			first, err := cancelled(ctx, req)
+			assert.NilError(t, err)
+
+			second, err := create(ContextWithIdempotencyKey(context.TODO(), "key-3"), req)
+			assert.NilError(t, err)
+			assert.Equal(t, calls, 1)
+			assert.Equal(t, second.(CreateResponse).ID, first.(CreateResponse).ID)
+		})
+}
+
+// contextRepo fails the calls made on a done context, as the Mongo repository does
+type contextRepo struct {
+	domainRepo.IIdempotencyRepository
+}
+
+func (r contextRepo) Complete(ctx context.Context, key string, response []byte) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return r.IIdempotencyRepository.Complete(ctx, key, response)
+}
+
+func (r contextRepo) Release(ctx context.Context, key string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return r.IIdempotencyRepository.Release(ctx, key)
+}
@@ -0,0 +1,66 @@
+package endpoints
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// BuildInfo describes the build of the binary, injected at link time
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// InstanceInfo describes the running instance
+type InstanceInfo struct {
+	Build      BuildInfo
+	Repository string
+	Features   []string
+	StartTime  time.Time
+}
+
+// InfoRequest holds the request parameters for the Info method.
+type InfoRequest struct{}
+
+// InfoResponse holds the response values for the Info method.
+type InfoResponse struct {
+	BuildInfo
+	GoVersion  string    `json:"go_version"`
+	Hostname   string    `json:"hostname"`
+	StartTime  time.Time `json:"start_time"`
+	Uptime     string    `json:"uptime"`
+	Repository string    `json:"repository"`
+	Features   []string  `json:"features"`
+}
+
+// MakeInfoEndpoint returns the build and runtime information of the instance
+func MakeInfoEndpoint(info InstanceInfo) endpoint.Endpoint {
+	return makeInfoEndpoint(info, os.Hostname, time.Now)
+}
+
+func makeInfoEndpoint(info InstanceInfo, hostname func() (string, error), now func() time.Time) endpoint.Endpoint {
+	features := info.Features
+	if features == nil {
+		features = []string{}
+	}
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		name, err := hostname()
+		if err != nil {
+			return nil, err
+		}
+		return InfoResponse{
+			BuildInfo:  info.Build,
+			GoVersion:  runtime.Version(),
+			Hostname:   name,
+			StartTime:  info.StartTime.UTC(),
+			Uptime:     now().Sub(info.StartTime).Truncate(time.Second).String(),
+			Repository: info.Repository,
+			Features:   features,
+		}, nil
+	}
+}
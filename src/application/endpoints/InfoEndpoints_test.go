@@ -0,0 +1,42 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestInfoEndpoint(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	now := func() time.Time { return start.Add(90*time.Minute + 1500*time.Millisecond) }
+	info := InstanceInfo{
+		Build:      BuildInfo{Version: "1.2.0", Commit: "abc123", BuildTime: "2020-01-01T00:00:00Z"},
+		Repository: "memory",
+		StartTime:  start,
+	}
+
+	t.Run("WHEN the instance is running SHOULD return the build and runtime information", func(t *testing.T) {
+		e := makeInfoEndpoint(info, func() (string, error) { return "host-1", nil }, now)
+		response, err := e(context.TODO(), InfoRequest{})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, response, InfoResponse{
+			BuildInfo:  info.Build,
+			GoVersion:  runtime.Version(),
+			Hostname:   "host-1",
+			StartTime:  start.UTC(),
+			Uptime:     "1h30m1s",
+			Repository: "memory",
+			Features:   []string{},
+		})
+	})
+	t.Run("WHEN the hostname is unknown SHOULD fail", func(t *testing.T) {
+		failure := errors.New("no hostname")
+		e := makeInfoEndpoint(info, func() (string, error) { return "", failure }, now)
+		_, err := e(context.TODO(), InfoRequest{})
+		assert.Equal(t, err, failure)
+	})
+}
@@ -2,9 +2,12 @@ package endpoints
 
 import (
 	"context"
-	"os"
+	"io"
 
+	"microservice_gokit_base/src/application/codec"
+	"microservice_gokit_base/src/application/importer"
 	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/repository"
 	"microservice_gokit_base/src/domain/service"
 
 	"github.com/go-kit/kit/endpoint"
@@ -13,23 +16,92 @@ import (
 // IOrderEndpoints holds all Go kit endpoints for the Order service.
 type IOrderEndpoints interface {
 	CreateEndpoint() endpoint.Endpoint
+	CreateBatchEndpoint() endpoint.Endpoint
 	GetByIDEndpoint() endpoint.Endpoint
 	GetAllEndpoint() endpoint.Endpoint
+	ExportEndpoint() endpoint.Endpoint
+	ImportEndpoint() endpoint.Endpoint
 	ChangeStatusEndpoint() endpoint.Endpoint
+	ChangeStatusBulkEndpoint() endpoint.Endpoint
+	GetHistoryEndpoint() endpoint.Endpoint
 	CountEndpoint() endpoint.Endpoint
-	SaludoEndpoint() endpoint.Endpoint
 }
 
+// Names of the endpoints used to attach middlewares
+const (
+	CreateName           = "Create"
+	CreateBatchName      = "CreateBatch"
+	GetByIDName          = "GetByID"
+	GetAllName           = "GetAll"
+	ExportName           = "Export"
+	ImportName           = "Import"
+	ChangeStatusName     = "ChangeStatus"
+	ChangeStatusBulkName = "ChangeStatusBulk"
+	CountName            = "Count"
+	GetHistoryName       = "GetHistory"
+)
+
 // OrderEndpoints Struct to instanciate endpoints
 type OrderEndpoints struct {
 	orderDomainService service.IOrderService
+	middlewares        []namedMiddleware
+	importBatchSize    int
+}
+
+// Option configures the Order endpoints
+type Option func(*OrderEndpoints)
+
+type namedMiddleware struct {
+	middleware endpoint.Middleware
+	names      []string
+}
+
+// WithMiddleware wraps the named endpoints, or all of them when no name is given,
+// with the middleware. Middlewares given first are the outermost.
+func WithMiddleware(mw endpoint.Middleware, names ...string) Option {
+	return func(s *OrderEndpoints) {
+		s.middlewares = append(s.middlewares, namedMiddleware{middleware: mw, names: names})
+	}
+}
+
+// WithImportBatchSize sets the number of orders stored at once by the imports
+func WithImportBatchSize(size int) Option {
+	return func(s *OrderEndpoints) {
+		s.importBatchSize = size
+	}
 }
 
 // MakeOrderEndpoints initializes all Go kit endpoints for the Order service.
-func MakeOrderEndpoints(s service.IOrderService) IOrderEndpoints {
-	return &OrderEndpoints{
+func MakeOrderEndpoints(s service.IOrderService, options ...Option) IOrderEndpoints {
+	e := &OrderEndpoints{
 		orderDomainService: s,
 	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// wrap applies the middlewares registered for the endpoint name
+func (s *OrderEndpoints) wrap(name string, e endpoint.Endpoint) endpoint.Endpoint {
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		if appliesTo(s.middlewares[i].names, name) {
+			e = s.middlewares[i].middleware(e)
+		}
+	}
+	return e
+}
+
+func appliesTo(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
 // CreateRequest holds the request parameters for the Create method.
@@ -40,7 +112,7 @@ type CreateRequest struct {
 // CreateResponse holds the response values for the Create method.
 type CreateResponse struct {
 	ID  string `json:"id"`
-	Err error  `json:"error,omitempty"`
+	Err error  `json:"-"`
 }
 
 // Failed implements endpoint.Failer.
@@ -48,11 +120,52 @@ func (r CreateResponse) Failed() error { return r.Err }
 
 // CreateEndpoint Service to expose domain logic
 func (s *OrderEndpoints) CreateEndpoint() endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
+	return s.wrap(CreateName, func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(CreateRequest)
 		id, err := s.orderDomainService.Create(ctx, req.Order)
 		return CreateResponse{ID: id, Err: err}, nil
-	}
+	})
+}
+
+// CreateBatchRequest holds the request parameters for the CreateBatch method.
+type CreateBatchRequest struct {
+	Orders []model.Order
+}
+
+// CreateBatchResult holds the outcome of an order of the batch.
+type CreateBatchResult struct {
+	Index  int                  `json:"index"`
+	ID     string               `json:"id,omitempty"`
+	Error  string               `json:"error,omitempty"`
+	Fields []service.FieldError `json:"fields,omitempty"`
+}
+
+// CreateBatchResponse holds the response values for the CreateBatch method.
+type CreateBatchResponse struct {
+	Results []CreateBatchResult `json:"result"`
+	Err     error               `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r CreateBatchResponse) Failed() error { return r.Err }
+
+// CreateBatchEndpoint Service to expose domain logic
+func (s *OrderEndpoints) CreateBatchEndpoint() endpoint.Endpoint {
+	return s.wrap(CreateBatchName, func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateBatchRequest)
+		created, err := s.orderDomainService.CreateBatch(ctx, req.Orders)
+		results := make([]CreateBatchResult, len(created))
+		for i, c := range created {
+			results[i] = CreateBatchResult{Index: i, ID: c.ID}
+			if c.Err != nil {
+				results[i].Error = c.Err.Error()
+			}
+			if invalid, ok := c.Err.(service.ValidationError); ok {
+				results[i].Fields = invalid.Fields
+			}
+		}
+		return CreateBatchResponse{Results: results, Err: err}, nil
+	})
 }
 
 // GetByIDRequest holds the request parameters for the GetByID method.
@@ -63,31 +176,32 @@ type GetByIDRequest struct {
 // GetByIDResponse holds the response values for the GetByID method.
 type GetByIDResponse struct {
 	Order model.Order `json:"result"`
-	Err   error       `json:"error,omitempty"`
+	Err   error       `json:"-"`
 }
 
 // Failed implements endpoint.Failer.
 func (r GetByIDResponse) Failed() error { return r.Err }
 
-//GetByIDEndpoint Service to expose domain logic
+// GetByIDEndpoint Service to expose domain logic
 func (s *OrderEndpoints) GetByIDEndpoint() endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
+	return s.wrap(GetByIDName, func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(GetByIDRequest)
 		orderRes, err := s.orderDomainService.GetByID(ctx, req.ID)
 		return GetByIDResponse{Order: orderRes, Err: err}, nil
-	}
+	})
 }
 
 // GetAllRequest holds the request parameters for the GetAll method.
 type GetAllRequest struct {
-	Page int64 `json:"page"`
-	Size int64 `json:"size"`
+	Page   int64                  `json:"page"`
+	Size   int64                  `json:"size"`
+	Filter repository.OrderFilter `json:"-"`
 }
 
 // GetlAllResponse holds the response values for the GetAll method.
 type GetlAllResponse struct {
 	Orders []*model.Order `json:"result"`
-	Err    error          `json:"error,omitempty"`
+	Err    error          `json:"-"`
 }
 
 // Failed implements endpoint.Failer.
@@ -95,20 +209,96 @@ func (r GetlAllResponse) Failed() error { return r.Err }
 
 // GetAllEndpoint Service to expose domain logic
 func (s *OrderEndpoints) GetAllEndpoint() endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
+	return s.wrap(GetAllName, func(ctx context.Context, request interface{}) (interface{}, error) {
 		var err error
 		var orders []*model.Order
 		req := request.(GetAllRequest)
 		if req.Size > 0 {
-			orders, err = s.orderDomainService.GetPage(ctx, req.Page, req.Size)
+			orders, err = s.orderDomainService.GetPage(ctx, req.Filter, req.Page, req.Size)
 		} else {
-			orders, err = s.orderDomainService.GetAll(ctx)
+			orders, err = s.orderDomainService.GetAll(ctx, req.Filter)
 		}
 		if orders == nil {
 			orders = make([]*model.Order, 0)
 		}
 		return GetlAllResponse{Orders: orders, Err: err}, nil
+	})
+}
+
+// ExportRequest holds the request parameters for the Export method.
+type ExportRequest struct {
+	Format string
+	Filter repository.OrderFilter
+}
+
+// ExportResponse holds the response values for the Export method, the orders
+// are streamed by the transport while they are read from the repository.
+type ExportResponse struct {
+	Format string
+	Orders repository.IOrderIterator
+	Err    error
+}
+
+// Failed implements endpoint.Failer.
+func (r ExportResponse) Failed() error { return r.Err }
+
+func (r ExportResponse) releaseOnClose(release func()) interface{} {
+	if r.Orders == nil {
+		release()
+		return r
 	}
+	r.Orders = closeNotifier{IOrderIterator: r.Orders, onClose: release}
+	return r
+}
+
+// closeNotifier calls onClose after closing the iterator
+type closeNotifier struct {
+	repository.IOrderIterator
+	onClose func()
+}
+
+func (it closeNotifier) Close(ctx context.Context) error {
+	defer it.onClose()
+	return it.IOrderIterator.Close(ctx)
+}
+
+// ExportEndpoint Service to expose domain logic
+func (s *OrderEndpoints) ExportEndpoint() endpoint.Endpoint {
+	return s.wrap(ExportName, func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ExportRequest)
+		orders, err := s.orderDomainService.Export(ctx, req.Filter)
+		return ExportResponse{Format: req.Format, Orders: orders, Err: err}, nil
+	})
+}
+
+// ImportRequest holds the request parameters for the Import method.
+type ImportRequest struct {
+	Format string
+	File   io.Reader
+}
+
+// ImportResponse holds the response values for the Import method.
+type ImportResponse struct {
+	Summary  importer.Summary     `json:"result"`
+	Rejected []importer.Rejection `json:"rejected"`
+	Err      error                `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r ImportResponse) Failed() error { return r.Err }
+
+// ImportEndpoint Service to expose domain logic
+func (s *OrderEndpoints) ImportEndpoint() endpoint.Endpoint {
+	return s.wrap(ImportName, func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ImportRequest)
+		reader, err := codec.NewReader(req.Format, req.File)
+		if err != nil {
+			return ImportResponse{Err: err}, nil
+		}
+		report := &importer.ListReport{Rejections: []importer.Rejection{}}
+		summary, err := importer.NewImporter(s.orderDomainService, s.importBatchSize).Import(ctx, reader, report)
+		return ImportResponse{Summary: summary, Rejected: report.Rejections, Err: err}, nil
+	})
 }
 
 // CountRequest holds the request parameters for the GetPage method.
@@ -118,7 +308,7 @@ type CountRequest struct {
 // CountResponse holds the response values for the GetPage method.
 type CountResponse struct {
 	Count int64 `json:"result"`
-	Err   error `json:"error,omitempty"`
+	Err   error `json:"-"`
 }
 
 // Failed implements endpoint.Failer.
@@ -126,22 +316,29 @@ func (r CountResponse) Failed() error { return r.Err }
 
 // CountEndpoint Service to expose domain logic
 func (s *OrderEndpoints) CountEndpoint() endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
+	return s.wrap(CountName, func(ctx context.Context, request interface{}) (interface{}, error) {
 		count, err := s.orderDomainService.Count(ctx)
 		return CountResponse{Count: count, Err: err}, nil
-	}
+	})
 }
 
 // ChangeStatusRequest holds the request parameters for the ChangeStatus method.
 type ChangeStatusRequest struct {
 	ID     string `json:"id"`
 	Status string `json:"status"`
+	Actor  string `json:"actor,omitempty"`
+	Note   string `json:"note,omitempty"`
+	// ExpectedStatus makes the change only when the order is still in this status
+	ExpectedStatus string `json:"expected_status,omitempty"`
+	// ExpectedVersion is taken from the If-Match header, checked when CheckVersion is set
+	ExpectedVersion int64 `json:"-"`
+	CheckVersion    bool  `json:"-"`
 }
 
 // ChangeStatusResponse holds the response values for the ChangeStatus method.
 type ChangeStatusResponse struct {
 	Updated int64 `json:"updated"`
-	Err     error `json:"error,omitempty"`
+	Err     error `json:"-"`
 }
 
 // Failed implements endpoint.Failer.
@@ -149,30 +346,117 @@ func (r ChangeStatusResponse) Failed() error { return r.Err }
 
 // ChangeStatusEndpoint Service to expose domain logic
 func (s *OrderEndpoints) ChangeStatusEndpoint() endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
+	return s.wrap(ChangeStatusName, func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(ChangeStatusRequest)
-		changed, err := s.orderDomainService.ChangeStatus(ctx, req.ID, req.Status)
+		change := model.StatusChange{
+			Status: req.Status,
+			Actor:  req.Actor,
+			Note:   req.Note,
+		}
+		cond := repository.UpdateCondition{
+			Version:      req.ExpectedVersion,
+			CheckVersion: req.CheckVersion,
+			Status:       req.ExpectedStatus,
+		}
+		changed, err := s.orderDomainService.ChangeStatus(ctx, req.ID, change, cond)
 		return ChangeStatusResponse{Updated: changed, Err: err}, nil
-	}
+	})
 }
 
-// SaludoEndpoint funcion de pruebas
-func (s *OrderEndpoints) SaludoEndpoint() endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		name, err := os.Hostname()
-		if err != nil {
-			panic(err)
+// ChangeStatusBulkFilter selects the orders of a bulk status change.
+type ChangeStatusBulkFilter struct {
+	RestaurantID string `json:"restaurant_id,omitempty"`
+	Status       string `json:"status,omitempty"`
+}
+
+// ChangeStatusBulkRequest holds the request parameters for the ChangeStatusBulk method.
+type ChangeStatusBulkRequest struct {
+	IDs    []string               `json:"ids,omitempty"`
+	Filter ChangeStatusBulkFilter `json:"filter"`
+	Status string                 `json:"status"`
+	Actor  string                 `json:"actor,omitempty"`
+	Note   string                 `json:"note,omitempty"`
+}
+
+// ChangeStatusBulkResult holds the outcome of an order of the bulk change.
+type ChangeStatusBulkResult struct {
+	ID             string `json:"id"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Outcome        string `json:"outcome"`
+}
+
+// ChangeStatusBulkResponse holds the response values for the ChangeStatusBulk method.
+type ChangeStatusBulkResponse struct {
+	Updated int64                    `json:"updated"`
+	Results []ChangeStatusBulkResult `json:"result"`
+	Err     error                    `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r ChangeStatusBulkResponse) Failed() error { return r.Err }
+
+// ChangeStatusBulkEndpoint Service to expose domain logic
+func (s *OrderEndpoints) ChangeStatusBulkEndpoint() endpoint.Endpoint {
+	return s.wrap(ChangeStatusBulkName, func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ChangeStatusBulkRequest)
+		filter := repository.OrderFilter{
+			IDs:          req.IDs,
+			RestaurantID: req.Filter.RestaurantID,
+			Status:       req.Filter.Status,
+		}
+		change := model.StatusChange{
+			Status: req.Status,
+			Actor:  req.Actor,
+			Note:   req.Note,
+		}
+		changed, err := s.orderDomainService.ChangeStatusBulk(ctx, filter, change)
+		res := ChangeStatusBulkResponse{Results: make([]ChangeStatusBulkResult, len(changed)), Err: err}
+		for i, c := range changed {
+			res.Results[i] = ChangeStatusBulkResult{ID: c.ID, PreviousStatus: c.PreviousStatus, Outcome: c.Outcome}
+			if c.Outcome == model.OutcomeUpdated {
+				res.Updated++
+			}
 		}
+		return res, nil
+	})
+}
 
-		return "Hostname ( " + name + " ) saludos desde quito.", nil
-	}
+// GetHistoryRequest holds the request parameters for the GetHistory method.
+type GetHistoryRequest struct {
+	ID string
+}
+
+// GetHistoryResponse holds the response values for the GetHistory method.
+type GetHistoryResponse struct {
+	History []model.StatusChange `json:"result"`
+	Err     error                `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r GetHistoryResponse) Failed() error { return r.Err }
+
+// GetHistoryEndpoint Service to expose domain logic
+func (s *OrderEndpoints) GetHistoryEndpoint() endpoint.Endpoint {
+	return s.wrap(GetHistoryName, func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetHistoryRequest)
+		history, err := s.orderDomainService.GetHistory(ctx, req.ID)
+		if history == nil {
+			history = make([]model.StatusChange, 0)
+		}
+		return GetHistoryResponse{History: history, Err: err}, nil
+	})
 }
 
 // compile time assertions for our response types implementing endpoint.Failer.
 var (
 	_ endpoint.Failer = GetlAllResponse{}
+	_ endpoint.Failer = ExportResponse{}
+	_ endpoint.Failer = ImportResponse{}
 	_ endpoint.Failer = GetByIDResponse{}
 	_ endpoint.Failer = ChangeStatusResponse{}
+	_ endpoint.Failer = ChangeStatusBulkResponse{}
 	_ endpoint.Failer = CreateResponse{}
+	_ endpoint.Failer = CreateBatchResponse{}
 	_ endpoint.Failer = CountResponse{}
+	_ endpoint.Failer = GetHistoryResponse{}
 )
@@ -9,6 +9,7 @@ import (
 	"gotest.tools/assert"
 
 	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/repository"
 	"microservice_gokit_base/src/mocks"
 )
 
@@ -73,6 +74,51 @@ func TestApplicationOrderEndpoints(t *testing.T) {
 				})
 		})
 
+	t.Run("orderEndpoints.CreateBatchEndpoint",
+		func(t *testing.T) {
+			t.Run("WHEN everything is ok SHOULD return the result of each order",
+				func(t *testing.T) {
+
+					gomock.InOrder(
+						orderServiceDomain.EXPECT().CreateBatch(
+							ctx,
+							[]model.Order{order, order}).Return([]model.CreateResult{
+							{ID: order.ID},
+							{Err: mockError},
+						}, nil).Times(1),
+					)
+
+					req := CreateBatchRequest{
+						Orders: []model.Order{order, order},
+					}
+
+					ok, err := orderEndpoints.CreateBatchEndpoint()(ctx, req)
+					assert.NilError(t, err)
+					assert.DeepEqual(t, ok.(CreateBatchResponse).Results, []CreateBatchResult{
+						{Index: 0, ID: order.ID},
+						{Index: 1, Error: mockError.Error()},
+					})
+				})
+
+			t.Run("WHEN an error happend SHOULD return an error response",
+				func(t *testing.T) {
+
+					gomock.InOrder(
+						orderServiceDomain.EXPECT().CreateBatch(
+							ctx,
+							[]model.Order{order}).Return(nil, mockError).Times(1),
+					)
+
+					req := CreateBatchRequest{
+						Orders: []model.Order{order},
+					}
+
+					ok, err := orderEndpoints.CreateBatchEndpoint()(ctx, req)
+					assert.NilError(t, err)
+					assert.Assert(t, ok.(CreateBatchResponse).Err == mockError)
+				})
+		})
+
 	t.Run("orderEndpoints.GetByIDEndpoint",
 		func(t *testing.T) {
 			t.Run("WHEN everything is ok SHOULD return an correct response",
@@ -119,7 +165,7 @@ func TestApplicationOrderEndpoints(t *testing.T) {
 
 					gomock.InOrder(
 						orderServiceDomain.EXPECT().GetPage(
-							ctx, page, size).Return(listOrder, nil).Times(1),
+							ctx, repository.OrderFilter{}, page, size).Return(listOrder, nil).Times(1),
 					)
 
 					req := GetAllRequest{
@@ -137,7 +183,7 @@ func TestApplicationOrderEndpoints(t *testing.T) {
 
 					gomock.InOrder(
 						orderServiceDomain.EXPECT().GetAll(
-							ctx).Return(listOrder, nil).Times(1),
+							ctx, repository.OrderFilter{}).Return(listOrder, nil).Times(1),
 					)
 
 					req := GetAllRequest{}
@@ -152,7 +198,7 @@ func TestApplicationOrderEndpoints(t *testing.T) {
 
 					gomock.InOrder(
 						orderServiceDomain.EXPECT().GetAll(
-							ctx).Return(nil, mockError).Times(1),
+							ctx, repository.OrderFilter{}).Return(nil, mockError).Times(1),
 					)
 
 					req := GetAllRequest{}
@@ -200,4 +246,74 @@ func TestApplicationOrderEndpoints(t *testing.T) {
 					assert.Assert(t, ok == res)
 				})
 		})
+
+	t.Run("orderEndpoints.ChangeStatusBulkEndpoint",
+		func(t *testing.T) {
+			t.Run("WHEN everything is ok SHOULD return the outcome of each order",
+				func(t *testing.T) {
+
+					gomock.InOrder(
+						orderServiceDomain.EXPECT().ChangeStatusBulk(
+							ctx,
+							repository.OrderFilter{RestaurantID: "001", Status: "Pending"},
+							model.StatusChange{Status: "Cancelled"}).Return([]model.StatusChangeResult{
+							{ID: "1", PreviousStatus: "Pending", Outcome: model.OutcomeUpdated},
+							{ID: "2", PreviousStatus: "Pending", Outcome: model.OutcomeConflict},
+						}, nil).Times(1),
+					)
+
+					req := ChangeStatusBulkRequest{
+						Filter: ChangeStatusBulkFilter{RestaurantID: "001", Status: "Pending"},
+						Status: "Cancelled",
+					}
+
+					ok, err := orderEndpoints.ChangeStatusBulkEndpoint()(ctx, req)
+					assert.NilError(t, err)
+					assert.Equal(t, ok.(ChangeStatusBulkResponse).Updated, int64(1))
+					assert.Assert(t, len(ok.(ChangeStatusBulkResponse).Results) == 2)
+				})
+		})
+
+	t.Run("orderEndpoints.GetHistoryEndpoint",
+		func(t *testing.T) {
+			history := []model.StatusChange{
+				{Status: "Pending", Timestamp: 1},
+			}
+
+			t.Run("WHEN everything is ok SHOULD return an correct response",
+				func(t *testing.T) {
+
+					gomock.InOrder(
+						orderServiceDomain.EXPECT().GetHistory(
+							ctx,
+							order.ID).Return(history, nil).Times(1),
+					)
+
+					req := GetHistoryRequest{
+						ID: order.ID,
+					}
+
+					ok, err := orderEndpoints.GetHistoryEndpoint()(ctx, req)
+					assert.NilError(t, err)
+					assert.DeepEqual(t, ok.(GetHistoryResponse).History, history)
+				})
+
+			t.Run("WHEN an error happend SHOULD return an error response",
+				func(t *testing.T) {
+
+					gomock.InOrder(
+						orderServiceDomain.EXPECT().GetHistory(
+							ctx,
+							order.ID).Return(nil, mockError).Times(1),
+					)
+
+					req := GetHistoryRequest{
+						ID: order.ID,
+					}
+
+					ok, err := orderEndpoints.GetHistoryEndpoint()(ctx, req)
+					assert.NilError(t, err)
+					assert.Assert(t, ok.(GetHistoryResponse).Err == mockError)
+				})
+		})
 }
@@ -0,0 +1,66 @@
+package endpoints
+
+import (
+	"context"
+
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/service"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-kit/kit/endpoint"
+)
+
+// principalRoles are the roles of the token claims, the first one held is taken
+var principalRoles = []string{model.RoleAdmin, model.RoleStaff, model.RoleCustomer}
+
+// WithPrincipal identifies the caller of every Order endpoint for NewAuthorizationService
+func WithPrincipal(secret []byte) Option {
+	return WithMiddleware(PrincipalMiddleware(secret))
+}
+
+// PrincipalMiddleware stores the caller of the request in the context. The callers with an
+// API key or a mutual TLS certificate granted scopes by WithAPIKeys are services, the ones with
+// a token signed by secret take the role, customer_id and restaurant_id of its claims. The
+// requests without credentials go on without caller, to be rejected by the authorization.
+func PrincipalMiddleware(secret []byte) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if key, ok := APIKeyIdentityFromContext(ctx); ok {
+				principal := model.Principal{Subject: "api-key:" + key.ID, Role: model.RoleService}
+				return next(service.ContextWithPrincipal(ctx, principal), request)
+			}
+			if raw := TokenFromContext(ctx); raw != "" {
+				claims, err := parseToken(secret, raw)
+				if err != nil {
+					return nil, err
+				}
+				principal, ok := principalFromClaims(claims)
+				if !ok {
+					return nil, ErrForbidden
+				}
+				recordSubject(ctx, principal.Subject)
+				ctx = context.WithValue(ctx, claimsContextKey, claims)
+				return next(service.ContextWithPrincipal(ctx, principal), request)
+			}
+			if identity, ok := TrustedClientFromContext(ctx); ok {
+				principal := model.Principal{Subject: identity.CommonName, Role: model.RoleService}
+				return next(service.ContextWithPrincipal(ctx, principal), request)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+func principalFromClaims(claims jwt.MapClaims) (model.Principal, bool) {
+	principal := model.Principal{}
+	principal.Subject, _ = claims["sub"].(string)
+	principal.CustomerID, _ = claims["customer_id"].(string)
+	principal.RestaurantID, _ = claims["restaurant_id"].(string)
+	for _, role := range principalRoles {
+		if hasRole(claims, role) {
+			principal.Role = role
+			return principal, true
+		}
+	}
+	return principal, false
+}
@@ -0,0 +1,53 @@
+package endpoints
+
+import (
+	"context"
+	"testing"
+
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/service"
+
+	"github.com/dgrijalva/jwt-go"
+	"gotest.tools/assert"
+)
+
+func TestPrincipalMiddleware(t *testing.T) {
+	secret := "secret"
+	next := func(ctx context.Context, request interface{}) (interface{}, error) {
+		principal, _ := service.PrincipalFromContext(ctx)
+		return principal, nil
+	}
+	e := PrincipalMiddleware([]byte(secret))(next)
+
+	t.Run("WHEN the token has a role SHOULD take the caller from its claims", func(t *testing.T) {
+		token := signToken(t, secret, jwt.MapClaims{"sub": "ana", "roles": []string{"viewer", "staff"}, "restaurant_id": "r1"})
+		var subject string
+		ctx := ContextWithSubjectRecorder(ContextWithToken(context.TODO(), token), func(s string) { subject = s })
+		principal, err := e(ctx, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, principal, model.Principal{Subject: "ana", Role: model.RoleStaff, RestaurantID: "r1"})
+		assert.Equal(t, subject, "ana")
+	})
+	t.Run("WHEN the token is invalid or has no known role SHOULD be rejected", func(t *testing.T) {
+		_, err := e(ContextWithToken(context.TODO(), signToken(t, "other", jwt.MapClaims{"role": "admin"})), nil)
+		assert.Equal(t, err, ErrTokenInvalid)
+		_, err = e(ContextWithToken(context.TODO(), signToken(t, secret, jwt.MapClaims{"role": "viewer"})), nil)
+		assert.Equal(t, err, ErrForbidden)
+	})
+	t.Run("WHEN the caller is a trusted mutual TLS client SHOULD be a service", func(t *testing.T) {
+		ctx := context.WithValue(context.TODO(), trustedClientContextKey, ClientIdentity{CommonName: "pos-1"})
+		principal, err := e(ctx, nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, principal, model.Principal{Subject: "pos-1", Role: model.RoleService})
+	})
+	t.Run("WHEN the mutual TLS client is not trusted SHOULD go on without caller", func(t *testing.T) {
+		principal, err := e(ContextWithClientIdentity(context.TODO(), ClientIdentity{CommonName: "pos-1"}), nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, principal, model.Principal{})
+	})
+	t.Run("WHEN there are no credentials SHOULD go on without caller", func(t *testing.T) {
+		principal, err := e(context.TODO(), nil)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, principal, model.Principal{})
+	})
+}
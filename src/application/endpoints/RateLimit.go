@@ -0,0 +1,77 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"microservice_gokit_base/src/application/ratelimit"
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// RateLimitedError when the client has run out of requests on the endpoint
+type RateLimitedError struct {
+	Result ratelimit.Result
+}
+
+func (e RateLimitedError) Error() string {
+	return fmt.Sprintf("too many requests, retry in %s", e.Result.RetryAfter)
+}
+
+// ContextWithClientIP returns a context carrying the address of the client of the request
+func ContextWithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPContextKey, ip)
+}
+
+// ContextWithRateLimitRecorder returns a context carrying the function told about the
+// rate limit of the request, as the transport sending it on the headers
+func ContextWithRateLimitRecorder(ctx context.Context, record func(ratelimit.Result)) context.Context {
+	return context.WithValue(ctx, rateLimitRecorderContextKey, record)
+}
+
+// WithRateLimits limits the requests of each client to the named endpoints, the endpoints
+// missing from limits are not limited
+func WithRateLimits(store ratelimit.IStore, limits map[string]ratelimit.Limit, logger log.Logger) Option {
+	return func(s *OrderEndpoints) {
+		for name, limit := range limits {
+			WithMiddleware(RateLimitMiddleware(store, limit, name, logger), name)(s)
+		}
+	}
+}
+
+// RateLimitMiddleware takes a token of the bucket of the client on the endpoint, failing with
+// RateLimitedError when there is none. It goes before the authentication middlewares so the
+// requests with wrong credentials are limited too, the clients are told apart by the certificate
+// verified on the TLS handshake or by their address. The requests are let through when the
+// store fails.
+func RateLimitMiddleware(store ratelimit.IStore, limit ratelimit.Limit, name string, logger log.Logger) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			result, err := store.Take(ctx, name+":"+rateLimitClient(ctx), limit, time.Now())
+			if err != nil {
+				level.Error(utils.LoggerFromContext(ctx, logger)).Log("msg", "unable to apply the rate limit", "err", err)
+				return next(ctx, request)
+			}
+			if record, ok := ctx.Value(rateLimitRecorderContextKey).(func(ratelimit.Result)); ok {
+				record(result)
+			}
+			if !result.Allowed {
+				return nil, RateLimitedError{Result: result}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// rateLimitClient returns the key of the client of the request, known before its credentials are checked
+func rateLimitClient(ctx context.Context) string {
+	if identity, ok := ClientIdentityFromContext(ctx); ok {
+		return "cn:" + identity.CommonName
+	}
+	ip, _ := ctx.Value(clientIPContextKey).(string)
+	return "ip:" + ip
+}
@@ -0,0 +1,41 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+)
+
+// ErrPanic when the handling of a request panicked, the details are only logged
+var ErrPanic = errors.New("the request could not be handled because of an internal error")
+
+// WithRecovery turns the panics of every endpoint into ErrPanic, it should be the first option
+// so the other middlewares are recovered too
+func WithRecovery(logger log.Logger, panics metrics.Counter) Option {
+	return WithMiddleware(RecoverMiddleware(logger, panics))
+}
+
+// RecoverMiddleware turns the panics of the endpoint into ErrPanic, logging the stack trace
+// and counting them on the panics counter
+func RecoverMiddleware(logger log.Logger, panics metrics.Counter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					panics.Add(1)
+					level.Error(utils.LoggerFromContext(ctx, logger)).Log(
+						"msg", "endpoint panic", "panic", r, "stack", string(debug.Stack()))
+					response, err = nil, ErrPanic
+				}
+			}()
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
+	"gotest.tools/assert"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	panics := generic.NewCounter("panics")
+	mw := RecoverMiddleware(log.NewLogfmtLogger(&buf), panics)
+
+	t.Run("WHEN the endpoint panics SHOULD return ErrPanic, log the stack and count it", func(t *testing.T) {
+		e := mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+			var order *struct{ ID string }
+			return order.ID, nil
+		})
+		response, err := e(context.TODO(), nil)
+		assert.Equal(t, err, ErrPanic)
+		assert.Assert(t, response == nil)
+		assert.Equal(t, panics.Value(), float64(1))
+		assert.Assert(t, strings.Contains(buf.String(), "nil pointer dereference"))
+		assert.Assert(t, strings.Contains(buf.String(), "Recover_test.go"))
+	})
+	t.Run("WHEN the endpoint does not panic SHOULD return its response", func(t *testing.T) {
+		e := mw(func(ctx context.Context, request interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+		response, err := e(context.TODO(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, response, "ok")
+		assert.Equal(t, panics.Value(), float64(1))
+	})
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"microservice_gokit_base/src/application/codec"
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/service"
+)
+
+// Rejection holds an order of the file that was not imported
+type Rejection struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// Summary holds the counts of an import
+type Summary struct {
+	Read     int `json:"read"`
+	Imported int `json:"imported"`
+	Rejected int `json:"rejected"`
+}
+
+// Report receives the orders rejected by an import
+type Report interface {
+	Reject(rejection Rejection) error
+}
+
+// Importer stores in batches the orders read from a file
+type Importer struct {
+	svc       service.IOrderService
+	batchSize int
+}
+
+// NewImporter creates an importer writing batches of the given size
+func NewImporter(svc service.IOrderService, batchSize int) *Importer {
+	if batchSize < 1 {
+		batchSize = service.DefaultMaxBatchSize
+	}
+	return &Importer{svc: svc, batchSize: batchSize}
+}
+
+// Import reads every order of the reader and stores the valid ones, the invalid ones are sent to the report
+func (i *Importer) Import(ctx context.Context, reader codec.OrderReader, report Report) (Summary, error) {
+	var (
+		summary Summary
+		batch   = make([]codec.Record, 0, i.batchSize)
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.Read++
+		if record.Err != nil {
+			summary.Rejected++
+			if err := report.Reject(rejectionOf(record, record.Err)); err != nil {
+				return summary, err
+			}
+			continue
+		}
+		batch = append(batch, record)
+		if len(batch) == i.batchSize {
+			if err := i.flush(ctx, batch, &summary, report); err != nil {
+				return summary, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := i.flush(ctx, batch, &summary, report); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// flush stores a batch of records
+func (i *Importer) flush(ctx context.Context, batch []codec.Record, summary *Summary, report Report) error {
+	orders := make([]model.Order, len(batch))
+	for j := range batch {
+		orders[j] = batch[j].Order
+	}
+	results, err := i.svc.Import(ctx, orders)
+	if err != nil {
+		return err
+	}
+	for j, result := range results {
+		if result.Err == nil {
+			summary.Imported++
+			continue
+		}
+		summary.Rejected++
+		if err := report.Reject(rejectionOf(batch[j], result.Err)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rejectionOf(record codec.Record, err error) Rejection {
+	return Rejection{Line: record.Line, ID: record.Order.ID, Error: err.Error()}
+}
+
+// ListReport keeps the rejected orders in memory
+type ListReport struct {
+	Rejections []Rejection
+}
+
+// Reject implements Report.
+func (l *ListReport) Reject(rejection Rejection) error {
+	l.Rejections = append(l.Rejections, rejection)
+	return nil
+}
+
+// CSVReport writes the rejected orders as CSV rows of line, id and error
+type CSVReport struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSVReport creates a report writing on w
+func NewCSVReport(w io.Writer) *CSVReport {
+	return &CSVReport{w: csv.NewWriter(w)}
+}
+
+// Reject implements Report.
+func (c *CSVReport) Reject(rejection Rejection) error {
+	if !c.wroteHeader {
+		if err := c.w.Write([]string{"line", "id", "error"}); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	return c.w.Write([]string{strconv.Itoa(rejection.Line), rejection.ID, rejection.Error})
+}
+
+// Flush writes the buffered rows
+func (c *CSVReport) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
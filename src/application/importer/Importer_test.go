@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+
+	"microservice_gokit_base/src/application/codec"
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/mocks"
+)
+
+func TestImporter(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		orderService = mocks.NewMockIOrderService(mockCtrl)
+		ctx          = context.TODO()
+		mockError    = errors.New("errors")
+		file         = `{"id":"1","restaurant_id":"A"}` + "\n" +
+			`{"id":"2","restaurant_id":"A"}` + "\n" +
+			"{oops\n" +
+			`{"id":"3","restaurant_id":"A"}` + "\n"
+	)
+
+	t.Run("Importer.Import",
+		func(t *testing.T) {
+			t.Run("WHEN some rows are invalid SHOULD import the rest in batches and report the rejected ones",
+				func(t *testing.T) {
+					gomock.InOrder(
+						orderService.EXPECT().Import(ctx, []model.Order{
+							{ID: "1", RestaurantID: "A"},
+							{ID: "2", RestaurantID: "A"},
+						}).Return([]model.CreateResult{{ID: "1"}, {Err: mockError}}, nil).Times(1),
+						orderService.EXPECT().Import(ctx, []model.Order{
+							{ID: "3", RestaurantID: "A"},
+						}).Return([]model.CreateResult{{ID: "3"}}, nil).Times(1),
+					)
+
+					report := &ListReport{}
+					summary, err := NewImporter(orderService, 2).Import(ctx, codec.NewNDJSONReader(strings.NewReader(file)), report)
+					assert.NilError(t, err)
+					assert.DeepEqual(t, summary, Summary{Read: 4, Imported: 2, Rejected: 2})
+					assert.Assert(t, len(report.Rejections) == 2)
+					assert.DeepEqual(t, report.Rejections[0], Rejection{Line: 2, ID: "2", Error: "errors"})
+					assert.Equal(t, report.Rejections[1].Line, 3)
+				})
+
+			t.Run("WHEN the service fails SHOULD stop the import",
+				func(t *testing.T) {
+					orderService.EXPECT().Import(ctx, gomock.Any()).Return(nil, mockError).Times(1)
+
+					_, err := NewImporter(orderService, 10).Import(ctx, codec.NewNDJSONReader(strings.NewReader(file)), &ListReport{})
+					assert.Assert(t, err == mockError)
+				})
+		})
+
+	t.Run("CSVReport.Reject",
+		func(t *testing.T) {
+			t.Run("WHEN orders are rejected SHOULD write a row for each one",
+				func(t *testing.T) {
+					var buf bytes.Buffer
+					report := NewCSVReport(&buf)
+					assert.NilError(t, report.Reject(Rejection{Line: 3, ID: "1", Error: "RestaurantID: zero value"}))
+					assert.NilError(t, report.Flush())
+					assert.Equal(t, buf.String(), "line,id,error\n3,1,RestaurantID: zero value\n")
+				})
+		})
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of takes between the removals of the full buckets
+const sweepEvery = 1000
+
+type entry struct {
+	bucket Bucket
+	limit  Limit
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]entry
+	takes   int
+}
+
+// NewMemoryStore returns a store keeping the buckets of this instance in memory,
+// the buckets refilled completely are removed from time to time
+func NewMemoryStore() IStore {
+	return &memoryStore{buckets: map[string]entry{}}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+	e, ok := s.buckets[key]
+	if !ok {
+		e.bucket = NewBucket(limit, now)
+	}
+	bucket, result := e.bucket.Take(limit, now)
+	s.buckets[key] = entry{bucket: bucket, limit: limit}
+	return result, nil
+}
+
+func (s *memoryStore) sweep(now time.Time) {
+	for key, e := range s.buckets {
+		if e.bucket.Full(e.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+}
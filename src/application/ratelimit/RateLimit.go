@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit when a limit is not written as "<requests>/<s|m|h>" with an optional ":<burst>"
+var ErrInvalidLimit = errors.New(`the limit must be like "10/s", "600/m" or "600/m:50"`)
+
+// Limit is a token bucket refilled with Rate tokens per second holding at most Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit reads a limit like "10/s", "600/m" or "1000/h", the burst is the number of
+// requests of the period unless it is given after a colon, as in "600/m:50"
+func ParseLimit(s string) (Limit, error) {
+	spec, burstSpec := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		spec, burstSpec = s[:i], s[i+1:]
+	}
+	parts := strings.Split(strings.TrimSpace(spec), "/")
+	if len(parts) != 2 {
+		return Limit{}, ErrInvalidLimit
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests < 1 {
+		return Limit{}, ErrInvalidLimit
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[strings.TrimSpace(parts[1])]
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+	limit := Limit{Rate: float64(requests) / period.Seconds(), Burst: requests}
+	if burstSpec != "" {
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burstSpec)); err != nil || limit.Burst < 1 {
+			return Limit{}, ErrInvalidLimit
+		}
+	}
+	return limit, nil
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request was not allowed
+	RetryAfter time.Duration
+}
+
+// IStore describes the storage of the buckets, shared by the instances to apply the limits
+// to the whole service or local to each one
+type IStore interface {
+	// Take takes a token from the bucket of the key
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Bucket is the state of a token bucket, stores only need to keep it by key
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// NewBucket returns a full bucket
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), Updated: now}
+}
+
+// Take refills the bucket up to now and takes a token when there is one
+func (b Bucket) Take(limit Limit, now time.Time) (Bucket, Result) {
+	b = b.refill(limit, now)
+	result := Result{Limit: limit.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.Tokens) / limit.Rate)
+	}
+	result.Remaining = int(math.Floor(b.Tokens))
+	result.Reset = seconds((float64(limit.Burst) - b.Tokens) / limit.Rate)
+	return b, result
+}
+
+// Full tells if the bucket has refilled completely at now, so it can be forgotten
+func (b Bucket) Full(limit Limit, now time.Time) bool {
+	return b.refill(limit, now).Tokens >= float64(limit.Burst)
+}
+
+func (b Bucket) refill(limit Limit, now time.Time) Bucket {
+	if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*limit.Rate)
+		b.Updated = now
+	}
+	return b
+}
+
+// seconds rounds up to whole seconds, as sent on the headers
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
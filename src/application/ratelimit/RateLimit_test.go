@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	t.Run("WHEN the limit is valid SHOULD read the rate and the burst", func(t *testing.T) {
+		cases := map[string]Limit{
+			"10/s":      {Rate: 10, Burst: 10},
+			"600/m":     {Rate: 10, Burst: 600},
+			"600/m:50":  {Rate: 10, Burst: 50},
+			" 36/h : 2": {Rate: 0.01, Burst: 2},
+		}
+		for spec, expected := range cases {
+			limit, err := ParseLimit(spec)
+			assert.NilError(t, err, spec)
+			assert.DeepEqual(t, limit, expected)
+		}
+	})
+	t.Run("WHEN the limit is malformed SHOULD fail", func(t *testing.T) {
+		for _, spec := range []string{"", "10", "0/s", "10/d", "x/s", "10/s:0", "10/s:x"} {
+			_, err := ParseLimit(spec)
+			assert.Equal(t, err, ErrInvalidLimit, spec)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.TODO()
+	limit := Limit{Rate: 1, Burst: 2}
+	start := time.Unix(1000, 0)
+
+	t.Run("WHEN the bucket runs out SHOULD reject until a token is refilled", func(t *testing.T) {
+		store := NewMemoryStore()
+		first, _ := store.Take(ctx, "a", limit, start)
+		assert.DeepEqual(t, first, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second})
+		second, _ := store.Take(ctx, "a", limit, start)
+		assert.DeepEqual(t, second, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second})
+		rejected, _ := store.Take(ctx, "a", limit, start.Add(500*time.Millisecond))
+		assert.DeepEqual(t, rejected, Result{Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second})
+		refilled, _ := store.Take(ctx, "a", limit, start.Add(1500*time.Millisecond))
+		assert.Assert(t, refilled.Allowed)
+	})
+	t.Run("WHEN the keys differ SHOULD use their own buckets", func(t *testing.T) {
+		store := NewMemoryStore()
+		store.Take(ctx, "a", limit, start)
+		store.Take(ctx, "a", limit, start)
+		other, _ := store.Take(ctx, "b", limit, start)
+		assert.Assert(t, other.Allowed)
+	})
+	t.Run("WHEN the buckets are full again SHOULD forget them", func(t *testing.T) {
+		store := NewMemoryStore().(*memoryStore)
+		store.Take(ctx, "a", limit, start)
+		store.sweep(start.Add(time.Second))
+		assert.Equal(t, len(store.buckets), 0)
+	})
+}
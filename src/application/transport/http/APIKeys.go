@@ -0,0 +1,23 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"microservice_gokit_base/src/application/endpoints"
+)
+
+// APIKeyHeader carries the API key of the service-to-service callers
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyToContext stores the API key of the request in the context, the subject
+// authenticated with it is recorded on the access log line
+func apiKeyToContext(ctx context.Context, r *http.Request) context.Context {
+	ctx = endpoints.ContextWithSubjectRecorder(ctx, func(subject string) {
+		SetAccessLogSubject(ctx, subject)
+	})
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return endpoints.ContextWithAPIKey(ctx, key)
+	}
+	return ctx
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/domain/model"
+	domainSvc "microservice_gokit_base/src/domain/service"
+	"microservice_gokit_base/src/mocks"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	svc := mocks.NewMockIOrderService(mockCtrl)
+	apiKeys := mocks.NewMockIAPIKeyService(mockCtrl)
+	router := NewHTTPOrder(endpoints.MakeOrderEndpoints(svc, endpoints.WithAPIKeys(apiKeys, endpoints.CredentialPolicy{Required: true})),
+		log.NewNopLogger(), testBaseURL)
+	var buf bytes.Buffer
+	handler := NewAccessLog(router, log.NewJSONLogger(&buf))
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		buf.Reset()
+		r := httptest.NewRequest("GET", testBaseURL+"orders/count", nil)
+		if key != "" {
+			r.Header.Set(APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("WHEN the key is missing SHOULD respond 401 asking for it", func(t *testing.T) {
+		w := serve("")
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+		assert.Equal(t, w.Header().Get("WWW-Authenticate"), `APIKey header="X-API-Key"`)
+	})
+	t.Run("WHEN the key has the scope SHOULD serve the route and log the key as subject", func(t *testing.T) {
+		apiKeys.EXPECT().Authenticate(gomock.Any(), "upk_read").
+			Return(model.APIKey{ID: "k1", Scopes: []string{model.ScopeOrdersRead}}, nil)
+		svc.EXPECT().Count(gomock.Any()).Return(int64(3), nil)
+		w := serve("upk_read")
+		assert.Equal(t, w.Code, http.StatusOK)
+		var line map[string]interface{}
+		assert.NilError(t, json.NewDecoder(&buf).Decode(&line))
+		assert.Equal(t, line["subject"], "api-key:k1")
+	})
+	t.Run("WHEN the key has not the scope SHOULD respond 403", func(t *testing.T) {
+		apiKeys.EXPECT().Authenticate(gomock.Any(), "upk_write").
+			Return(model.APIKey{ID: "k2", Scopes: []string{model.ScopeOrdersWrite}}, nil)
+		assert.Equal(t, serve("upk_write").Code, http.StatusForbidden)
+	})
+	t.Run("WHEN a key is issued with an unknown scope SHOULD respond 400", func(t *testing.T) {
+		assert.Equal(t, codeFrom(domainSvc.UnknownScopeError{Scope: "orders:delete"}), http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+// accessEntry collects the values of a request only known by the inner handlers
+type accessEntry struct {
+	route   string
+	subject string
+}
+
+type accessEntryContextKey struct{}
+
+// AccessLogOption configures the access log
+type AccessLogOption func(*accessLog)
+
+// WithAccessLogExclude skips the requests whose path starts with any of the prefixes
+func WithAccessLogExclude(prefixes ...string) AccessLogOption {
+	return func(a *accessLog) {
+		a.exclude = append(a.exclude, prefixes...)
+	}
+}
+
+// WithAccessLogSampling logs only a rate between 0 and 1 of the successful requests
+// of each route template, the routes missing are always logged
+func WithAccessLogSampling(rates map[string]float64) AccessLogOption {
+	return func(a *accessLog) {
+		a.rates = rates
+	}
+}
+
+type accessLog struct {
+	next    http.Handler
+	logger  log.Logger
+	exclude []string
+	rates   map[string]float64
+	sample  func() float64
+	now     func() time.Time
+}
+
+// NewAccessLog logs a line per request served by next, the format of the lines is the one of the logger
+func NewAccessLog(next http.Handler, logger log.Logger, options ...AccessLogOption) http.Handler {
+	a := &accessLog{
+		next:   next,
+		logger: logger,
+		sample: rand.Float64,
+		now:    time.Now,
+	}
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+func (a *accessLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, prefix := range a.exclude {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			a.next.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	start := a.now()
+	entry := &accessEntry{}
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	a.next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessEntryContextKey{}, entry)))
+
+	if rate, ok := a.rates[entry.route]; ok && recorder.status < http.StatusInternalServerError && a.sample() >= rate {
+		return
+	}
+	a.logger.Log(
+		"method", r.Method,
+		"route", entry.route,
+		"path", r.URL.Path,
+		"status", recorder.status,
+		"bytes", recorder.bytes,
+		"latency_ms", float64(a.now().Sub(start))/float64(time.Millisecond),
+		"remote_addr", r.RemoteAddr,
+		"subject", entry.subject,
+		"request_id", recorder.Header().Get(RequestIDHeader),
+	)
+}
+
+// SetAccessLogSubject records the authenticated user or client of the request on its access log line
+func SetAccessLogSubject(ctx context.Context, subject string) {
+	if entry, ok := ctx.Value(accessEntryContextKey{}).(*accessEntry); ok {
+		entry.subject = subject
+	}
+}
+
+// routeTemplateMiddleware records the template of the matched route on the access log line
+func routeTemplateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry, ok := r.Context().Value(accessEntryContextKey{}).(*accessEntry); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				entry.route, _ = route.GetPathTemplate()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder keeps the status and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Flush keeps the streamed responses working through the recorder
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/mocks"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	svc := mocks.NewMockIOrderService(mockCtrl)
+	svc.EXPECT().GetByID(gomock.Any(), "abc").Return(model.Order{}, domainRepo.ErrNotFound).AnyTimes()
+	svc.EXPECT().Count(gomock.Any()).Return(int64(1), nil).AnyTimes()
+	router := NewHTTPOrder(endpoints.MakeOrderEndpoints(svc), log.NewNopLogger(), testBaseURL)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetAccessLogSubject(r.Context(), "client-1")
+		router.ServeHTTP(w, r)
+	})
+	serve := func(handler http.Handler, path string) []map[string]interface{} {
+		buf.Reset()
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set(RequestIDHeader, "req-1")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		var lines []map[string]interface{}
+		decoder := json.NewDecoder(&buf)
+		for decoder.More() {
+			var line map[string]interface{}
+			assert.NilError(t, decoder.Decode(&line))
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	t.Run("WHEN a route is served SHOULD log its template, status and request id", func(t *testing.T) {
+		handler := NewAccessLog(inner, log.NewJSONLogger(&buf)).(*accessLog)
+		ticks := []time.Time{time.Unix(0, 0), time.Unix(0, int64(5*time.Millisecond))}
+		handler.now = func() time.Time {
+			tick := ticks[0]
+			ticks = ticks[1:]
+			return tick
+		}
+		lines := serve(handler, testBaseURL+"orders/id/abc")
+		assert.Equal(t, len(lines), 1)
+		line := lines[0]
+		assert.Equal(t, line["method"], "GET")
+		assert.Equal(t, line["route"], testBaseURL+"orders/id/{id}")
+		assert.Equal(t, line["path"], testBaseURL+"orders/id/abc")
+		assert.Equal(t, line["status"], float64(http.StatusNotFound))
+		assert.Assert(t, line["bytes"].(float64) > 0)
+		assert.Equal(t, line["latency_ms"], float64(5))
+		assert.Equal(t, line["subject"], "client-1")
+		assert.Equal(t, line["request_id"], "req-1")
+	})
+	t.Run("WHEN the path is excluded SHOULD not log it", func(t *testing.T) {
+		handler := NewAccessLog(inner, log.NewJSONLogger(&buf), WithAccessLogExclude("/health"))
+		assert.Equal(t, len(serve(handler, "/health/live")), 0)
+		assert.Equal(t, len(serve(handler, testBaseURL+"orders/count")), 1)
+	})
+	t.Run("WHEN the route is sampled SHOULD log only the sampled requests", func(t *testing.T) {
+		handler := NewAccessLog(inner, log.NewJSONLogger(&buf),
+			WithAccessLogSampling(map[string]float64{testBaseURL + "orders/count": 0.5})).(*accessLog)
+		handler.sample = func() float64 { return 0.7 }
+		assert.Equal(t, len(serve(handler, testBaseURL+"orders/count")), 0)
+		handler.sample = func() float64 { return 0.2 }
+		assert.Equal(t, len(serve(handler, testBaseURL+"orders/count")), 1)
+	})
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"microservice_gokit_base/src/application/endpoints"
+
+	"github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// AdminPath is the base URL the admin routes are mounted on
+const AdminPath = "/admin/"
+
+// NewHTTPAdmin wires the admin Go kit endpoints to the HTTP transport.
+func NewHTTPAdmin(
+	adminEndpoints endpoints.IAdminEndpoints,
+	logger log.Logger, baseURL string,
+) http.Handler {
+	r := newRouter()
+	options := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(bearerTokenToContext),
+	}
+	// HTTP Get - /admin/log-levels
+	r.Methods("GET").Path(baseURL + "log-levels").Handler(kithttp.NewServer(
+		adminEndpoints.GetLogLevelsEndpoint(),
+		decodeGetLogLevelsRequest,
+		encodeResponse,
+		options...,
+	))
+	// HTTP Put - /admin/log-levels
+	r.Methods("PUT").Path(baseURL + "log-levels").Handler(kithttp.NewServer(
+		adminEndpoints.SetLogLevelEndpoint(),
+		decodeSetLogLevelRequest,
+		encodeResponse,
+		options...,
+	))
+	// HTTP Post - /admin/api-keys
+	r.Methods("POST").Path(baseURL + "api-keys").Handler(kithttp.NewServer(
+		adminEndpoints.IssueAPIKeyEndpoint(),
+		decodeIssueAPIKeyRequest,
+		encodeResponse,
+		options...,
+	))
+	// HTTP Delete - /admin/api-keys/{id}
+	r.Methods("DELETE").Path(baseURL + "api-keys/{id}").Handler(kithttp.NewServer(
+		adminEndpoints.RevokeAPIKeyEndpoint(),
+		decodeRevokeAPIKeyRequest,
+		encodeResponse,
+		options...,
+	))
+	// HTTP Get - /admin/vars
+	r.Methods("GET").Path(baseURL + "vars").Handler(kithttp.NewServer(
+		adminEndpoints.GetVarsEndpoint(),
+		decodeGetVarsRequest,
+		encodeResponse,
+		options...,
+	))
+
+	return r
+}
+
+// bearerTokenToContext stores the token of the Authorization header in the context
+func bearerTokenToContext(ctx context.Context, r *http.Request) context.Context {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return endpoints.ContextWithToken(ctx, strings.TrimSpace(header[7:]))
+	}
+	return ctx
+}
+
+func decodeGetLogLevelsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return endpoints.GetLogLevelsRequest{}, nil
+}
+
+func decodeGetVarsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return endpoints.GetVarsRequest{}, nil
+}
+
+func decodeSetLogLevelRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req endpoints.SetLogLevelRequest
+	if e := decodeJSON(r, &req, DefaultBodyLimits); e != nil {
+		return nil, e
+	}
+	return req, nil
+}
+
+func decodeIssueAPIKeyRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req endpoints.IssueAPIKeyRequest
+	if e := decodeJSON(r, &req, DefaultBodyLimits); e != nil {
+		return nil, e
+	}
+	return req, nil
+}
+
+func decodeRevokeAPIKeyRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return endpoints.RevokeAPIKeyRequest{ID: mux.Vars(r)["id"]}, nil
+}
@@ -3,11 +3,20 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"microservice_gokit_base/src/application/codec"
+	"microservice_gokit_base/src/application/endpoints"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	domainSvc "microservice_gokit_base/src/domain/service"
+	"microservice_gokit_base/src/domain/utils"
+
 	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
 )
 
 var (
@@ -19,8 +28,21 @@ var (
 	ErrBadRequest = func(e error) error {
 		return fmt.Errorf("the request was malformed: %s", e.Error())
 	}
+	// ErrWeakETag when If-Match has a weak entity tag, which never matches as it needs a strong comparison
+	ErrWeakETag = errors.New("If-Match needs a strong entity tag")
+	// errorRedactor masks the personal data of the error messages sent to the clients
+	errorRedactor = utils.NewDefaultRedactor()
 )
 
+// newRouter returns a router with the middlewares shared by every route
+func newRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.Use(requestIDMiddleware(utils.NewUUIDGenerator()), instanceMiddleware, routeTemplateMiddleware, clientIdentityMiddleware)
+	r.NotFoundHandler = problemHandler(ErrRouteNotFound)
+	r.MethodNotAllowedHandler = problemHandler(ErrMethodNotAllowed)
+	return r
+}
+
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if e, ok := response.(endpoint.Failer); ok && e.Failed() != nil {
 		encodeError(ctx, e.Failed(), w)
@@ -30,18 +52,70 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 	return json.NewEncoder(w).Encode(response)
 }
 
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
+// encodeError answers the error as an RFC 7807 problem
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 	if err == nil {
 		panic("encodeError with nil error")
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(codeFrom(err))
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err.Error(),
-	})
+	code := codeFrom(err)
+	rateLimitHeaders(ctx, w)
+	if err == endpoints.ErrShed {
+		w.Header().Set("Retry-After", "1")
+	}
+	if err == endpoints.ErrAPIKeyMissing || err == endpoints.ErrAPIKeyInvalid {
+		w.Header().Set("WWW-Authenticate", `APIKey header="`+APIKeyHeader+`"`)
+	} else if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.Header().Set("Content-Type", ProblemContentType+"; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(newProblem(ctx, err, code))
 }
 
 func codeFrom(err error) int {
+	if bad, ok := err.(RequestBodyError); ok {
+		return bad.Status
+	}
+	if _, ok := err.(domainRepo.StatusConflictError); ok {
+		return http.StatusConflict
+	}
+	if _, ok := err.(domainSvc.ValidationError); ok {
+		return http.StatusUnprocessableEntity
+	}
+	if _, ok := err.(domainSvc.PolicyError); ok {
+		return http.StatusUnprocessableEntity
+	}
+	if _, ok := err.(endpoints.InvalidLogLevelError); ok {
+		return http.StatusBadRequest
+	}
+	if _, ok := err.(domainSvc.UnknownScopeError); ok {
+		return http.StatusBadRequest
+	}
+	if _, ok := err.(endpoints.RateLimitedError); ok {
+		return http.StatusTooManyRequests
+	}
+	switch err {
+	case domainRepo.ErrNotFound, ErrRouteNotFound:
+		return http.StatusNotFound
+	case ErrMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case domainRepo.ErrVersionConflict, ErrWeakETag:
+		return http.StatusPreconditionFailed
+	case codec.ErrUnknownFormat:
+		return http.StatusBadRequest
+	case domainSvc.ErrEmptyBatch, domainSvc.ErrBatchTooLarge, domainSvc.ErrBulkWithoutTarget:
+		return http.StatusBadRequest
+	case endpoints.ErrIdempotencyKeyReused:
+		return http.StatusUnprocessableEntity
+	case endpoints.ErrIdempotencyInProgress:
+		return http.StatusConflict
+	case domainSvc.ErrAPIKeyWithoutName, domainSvc.ErrAPIKeyWithoutScopes:
+		return http.StatusBadRequest
+	case endpoints.ErrForbidden, endpoints.ErrScopeMissing, domainSvc.ErrNotAllowed:
+		return http.StatusForbidden
+	case endpoints.ErrShed, endpoints.ErrTimeout:
+		return http.StatusServiceUnavailable
+	}
 	if strings.HasPrefix(err.Error(), "the request was malformed:") {
 		return http.StatusBadRequest
 	}
@@ -53,3 +127,30 @@ func codeFrom(err error) int {
 	}
 	return http.StatusInternalServerError
 }
+
+// formatETag returns the entity tag of an order version
+func formatETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// parseIfMatch returns the version expected by an If-Match header, not checked when
+// the header is empty or matches any version; "0" is the tag of the orders stored without a version.
+// The weak tags fail with ErrWeakETag since If-Match compares the tags strongly (RFC 7232).
+func parseIfMatch(header string) (version int64, check bool, err error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, false, ErrWeakETag
+	}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false, errors.New("invalid If-Match header")
+	}
+	version, err = strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 0 {
+		return 0, false, errors.New("invalid If-Match header")
+	}
+	return version, true, nil
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestEncodeError(t *testing.T) {
+	t.Run("WHEN the error has personal data SHOULD mask it on the body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		encodeError(context.TODO(), errors.New(`write failed { customer_id: "C-0042", email: "jane@example.com" }`), w)
+		assert.Equal(t, w.Code, http.StatusInternalServerError)
+		assert.Assert(t, !strings.Contains(w.Body.String(), "C-0042"))
+		assert.Assert(t, !strings.Contains(w.Body.String(), "jane@example.com"))
+	})
+}
+
+func TestParseIfMatch(t *testing.T) {
+	t.Run("WHEN the header is a version tag SHOULD check that version, zero included", func(t *testing.T) {
+		for header, expected := range map[string]int64{`"3"`: 3, `"0"`: 0} {
+			version, check, err := parseIfMatch(header)
+			assert.NilError(t, err)
+			assert.Assert(t, check)
+			assert.Equal(t, version, expected)
+		}
+	})
+	t.Run("WHEN the header is empty or a wildcard SHOULD not check the version", func(t *testing.T) {
+		for _, header := range []string{"", "*"} {
+			_, check, err := parseIfMatch(header)
+			assert.NilError(t, err)
+			assert.Assert(t, !check)
+		}
+	})
+	t.Run("WHEN the header is a weak tag SHOULD fail the precondition", func(t *testing.T) {
+		_, check, err := parseIfMatch(`W/"3"`)
+		assert.Equal(t, err, ErrWeakETag)
+		assert.Assert(t, !check)
+		assert.Equal(t, codeFrom(err), http.StatusPreconditionFailed)
+	})
+	t.Run("WHEN the header is not a version tag SHOULD fail", func(t *testing.T) {
+		for _, header := range []string{"3", `"-1"`, `"x"`} {
+			_, _, err := parseIfMatch(header)
+			assert.Assert(t, err != nil)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ErrCORSCredentialsAnyOrigin when the credentials are allowed to any origin, letting every site
+// call the API as the signed in user
+var ErrCORSCredentialsAnyOrigin = errors.New(`the CORS credentials can not be allowed with the "*" origin`)
+
+// CORSConfig holds the cross-origin policy of the API
+type CORSConfig struct {
+	// AllowedOrigins like "https://app.example.com", "https://*.example.com" for
+	// any subdomain or "*" for any origin
+	AllowedOrigins   []string
+	AllowCredentials bool
+	// AllowedHeaders are added to the headers read by the routes
+	AllowedHeaders []string
+	MaxAge         time.Duration
+}
+
+// corsHeaders are the request headers read by the routes besides the documented parameters
+var corsHeaders = []string{"Accept", "Authorization", "Content-Type", APIKeyHeader}
+
+// corsExposedHeaders are the response headers the browsers let the clients read
+var corsExposedHeaders = []string{
+	"ETag", RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+}
+
+type corsRoute struct {
+	pattern *regexp.Regexp
+	methods []string
+}
+
+type cors struct {
+	next           http.Handler
+	config         CORSConfig
+	routes         []corsRoute
+	allowedHeaders string
+}
+
+// NewCORS applies the cross-origin policy to next, answering the preflight requests
+// of the routes of the handlers, as the ones of NewHTTPOrder, with the methods they
+// are registered with. It fails with ErrCORSCredentialsAnyOrigin when the credentials
+// are allowed with the "*" origin
+func NewCORS(next http.Handler, config CORSConfig, handlers ...http.Handler) (http.Handler, error) {
+	if config.AllowCredentials {
+		for _, allowed := range config.AllowedOrigins {
+			if allowed == "*" {
+				return nil, ErrCORSCredentialsAnyOrigin
+			}
+		}
+	}
+	c := &cors{next: next, config: config}
+	for _, handler := range handlers {
+		router, ok := handler.(*mux.Router)
+		if !ok {
+			continue
+		}
+		err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			expr, err := route.GetPathRegexp()
+			if err != nil {
+				return nil
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				return nil
+			}
+			pattern, err := regexp.Compile(expr)
+			if err != nil {
+				return err
+			}
+			c.routes = append(c.routes, corsRoute{pattern: pattern, methods: methods})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	headers := append(append([]string{}, corsHeaders...), config.AllowedHeaders...)
+	for _, route := range orderRoutes {
+		for _, param := range append([]paramDoc{requestIDParam}, route.Params...) {
+			if param.In == "header" {
+				headers = append(headers, param.Name)
+			}
+		}
+	}
+	c.allowedHeaders = strings.Join(uniqueSorted(headers, http.CanonicalHeaderKey), ", ")
+	return c, nil
+}
+
+func (c *cors) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Origin")
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if preflight {
+		methods := c.methods(r.URL.Path)
+		if len(methods) == 0 {
+			c.next.ServeHTTP(w, r)
+			return
+		}
+		if c.allowsOrigin(origin) {
+			c.setOrigin(w, origin)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", c.allowedHeaders)
+			if c.config.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.config.MaxAge/time.Second)))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if c.allowsOrigin(origin) {
+		c.setOrigin(w, origin)
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+	}
+	c.next.ServeHTTP(w, r)
+}
+
+// setOrigin allows the origin, echoing it when credentials are allowed since "*" is not valid with them
+func (c *cors) setOrigin(w http.ResponseWriter, origin string) {
+	if c.config.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		return
+	}
+	for _, allowed := range c.config.AllowedOrigins {
+		if allowed == "*" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			return
+		}
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *cors) allowsOrigin(origin string) bool {
+	for _, allowed := range c.config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		i := strings.Index(allowed, "://*.")
+		if i < 0 {
+			continue
+		}
+		scheme, domain := allowed[:i+3], strings.ToLower(allowed[i+4:])
+		if strings.HasPrefix(origin, scheme) && strings.HasSuffix(strings.ToLower(origin), domain) &&
+			len(origin) > len(scheme)+len(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// methods returns the methods of the routes matching the path, with OPTIONS
+func (c *cors) methods(path string) []string {
+	var methods []string
+	for _, route := range c.routes {
+		if route.pattern.MatchString(path) {
+			methods = append(methods, route.methods...)
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	return uniqueSorted(append(methods, http.MethodOptions), strings.ToUpper)
+}
+
+func uniqueSorted(values []string, normalize func(string) string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, value := range values {
+		value = normalize(value)
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"microservice_gokit_base/src/application/endpoints"
+
+	"github.com/go-kit/kit/log"
+	"gotest.tools/assert"
+)
+
+func TestCORS(t *testing.T) {
+	router := NewHTTPOrder(endpoints.MakeOrderEndpoints(nil), log.NewNopLogger(), testBaseURL)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	newCORS := func(config CORSConfig) http.Handler {
+		handler, err := NewCORS(next, config, router)
+		assert.NilError(t, err)
+		return handler
+	}
+	preflight := func(handler http.Handler, path string, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", path, nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", "PUT")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("WHEN the preflight is for a known route SHOULD answer 204 with its methods", func(t *testing.T) {
+		handler := newCORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: 10 * time.Minute})
+		w := preflight(handler, testBaseURL+"orders/status", "https://app.example.com")
+		assert.Equal(t, w.Code, http.StatusNoContent)
+		assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "https://app.example.com")
+		assert.Equal(t, w.Header().Get("Access-Control-Allow-Methods"), "OPTIONS, PUT")
+		assert.Equal(t, w.Header().Get("Access-Control-Max-Age"), "600")
+		headers := w.Header().Get("Access-Control-Allow-Headers")
+		for _, header := range []string{"Authorization", "Content-Type", "If-Match", "Idempotency-Key", "X-Request-Id"} {
+			assert.Assert(t, strings.Contains(headers, header), "%s is not allowed: %s", header, headers)
+		}
+	})
+	t.Run("WHEN the preflight is for an unknown route SHOULD let the next handler answer", func(t *testing.T) {
+		handler := newCORS(CORSConfig{AllowedOrigins: []string{"*"}})
+		w := preflight(handler, testBaseURL+"unknown", "https://app.example.com")
+		assert.Equal(t, w.Code, http.StatusTeapot)
+	})
+	t.Run("WHEN the origin is a subdomain of a wildcard SHOULD allow it", func(t *testing.T) {
+		handler := newCORS(CORSConfig{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true})
+		w := preflight(handler, testBaseURL+"orders", "https://shop.example.com")
+		assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "https://shop.example.com")
+		assert.Equal(t, w.Header().Get("Access-Control-Allow-Credentials"), "true")
+		assert.Equal(t, w.Header().Get("Access-Control-Allow-Methods"), "GET, OPTIONS, POST")
+
+		for _, origin := range []string{"https://example.com", "https://evilexample.com", "http://shop.example.com"} {
+			w = preflight(handler, testBaseURL+"orders", origin)
+			assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "", origin)
+		}
+	})
+	t.Run("WHEN a simple request comes from an allowed origin SHOULD expose the headers", func(t *testing.T) {
+		handler := newCORS(CORSConfig{AllowedOrigins: []string{"*"}})
+		r := httptest.NewRequest("GET", testBaseURL+"orders/count", nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, w.Code, http.StatusTeapot)
+		assert.Equal(t, w.Header().Get("Access-Control-Allow-Origin"), "*")
+		assert.Equal(t, w.Header().Get("Access-Control-Expose-Headers"), "ETag, X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
+		assert.Equal(t, w.Header().Get("Vary"), "Origin")
+	})
+	t.Run("WHEN the credentials are allowed to any origin SHOULD fail", func(t *testing.T) {
+		_, err := NewCORS(next, CORSConfig{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}, router)
+		assert.Equal(t, err, ErrCORSCredentialsAnyOrigin)
+	})
+}
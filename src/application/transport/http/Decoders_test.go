@@ -0,0 +1,129 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// fuzzLimits keeps the fuzzed bodies small enough to hit the size limit
+var fuzzLimits = BodyLimits{MaxBytes: 256, DisallowUnknownFields: true}
+
+var jsonSeeds = []string{
+	`{"restaurant_id":"r1","order_items":[{"product_code":"P1","unit_price":1.5,"quantity":2}]}`,
+	`[{"restaurant_id":"r1"},{"restaurant_id":"r2","customer_id":"c1"}]`,
+	`{"id":"1","status":"Accepted","actor":"kitchen","expected_status":"Pending"}`,
+	`{"ids":["1","2"],"filter":{"restaurant_id":"r1","status":"Pending"},"status":"Cancelled"}`,
+	`{"component":"service","level":"debug"}`,
+	`{"name":"pos","scopes":["orders:read"]}`,
+	`{"order_items":[{"quantity":"2"}]}`,
+	`{"order_items":[{"colour":"red"}]}`,
+	`{"id":"1"} trailing`,
+	`{"id":`,
+	`null`,
+	``,
+}
+
+// fuzzJSONDecoder checks that the decoder never panics, accepts only single valid JSON values
+// sent as JSON within the size limit and fails with client errors otherwise
+func fuzzJSONDecoder(f *testing.F, decode kithttp.DecodeRequestFunc) {
+	for _, seed := range jsonSeeds {
+		f.Add("application/json", []byte(seed))
+	}
+	f.Add("text/plain", []byte(`{}`))
+	f.Add("application/problem+json; charset=utf-8", []byte(`{}`))
+	f.Fuzz(func(t *testing.T, contentType string, body []byte) {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		request, err := decode(context.TODO(), r)
+		if err != nil {
+			if code := codeFrom(err); code < 400 || code > 499 {
+				t.Fatalf("the error %q answers %d", err, code)
+			}
+			return
+		}
+		if request == nil {
+			t.Fatal("no request nor error")
+		}
+		if !json.Valid(body) || int64(len(body)) > fuzzLimits.MaxBytes {
+			t.Fatalf("the body %q was accepted", body)
+		}
+	})
+}
+
+func FuzzDecodeCreateRequest(f *testing.F) {
+	fuzzJSONDecoder(f, decodeCreateRequest(fuzzLimits))
+}
+
+func FuzzDecodeCreateBatchRequest(f *testing.F) {
+	fuzzJSONDecoder(f, decodeCreateBatchRequest(fuzzLimits))
+}
+
+func FuzzDecodeChangeStatusRequest(f *testing.F) {
+	fuzzJSONDecoder(f, decodeChangeStausRequest(fuzzLimits))
+}
+
+func FuzzDecodeChangeStatusBulkRequest(f *testing.F) {
+	fuzzJSONDecoder(f, decodeChangeStatusBulkRequest(fuzzLimits))
+}
+
+func FuzzDecodeSetLogLevelRequest(f *testing.F) {
+	fuzzJSONDecoder(f, decodeSetLogLevelRequest)
+}
+
+func FuzzDecodeIssueAPIKeyRequest(f *testing.F) {
+	fuzzJSONDecoder(f, decodeIssueAPIKeyRequest)
+}
+
+// FuzzDecodeHeaderAndQueryRequests fuzzes the decoders reading the headers and the query
+func FuzzDecodeHeaderAndQueryRequests(f *testing.F) {
+	f.Add("page=1&size=10&restaurant_id=r1", "", `"3"`)
+	f.Add("format=csv&status=Pending", "text/csv", "*")
+	f.Add("format=xml", "application/x-ndjson", `W/"0"`)
+	f.Add("page=x&size=-1&%zz", "", `"1`)
+	decoders := []kithttp.DecodeRequestFunc{
+		decodeGetAll, decodeExportRequest, decodeImportRequest(fuzzLimits), decodeCount,
+		decodeInfoRequest, decodeGetLogLevelsRequest,
+	}
+	f.Fuzz(func(t *testing.T, query string, contentType string, ifMatch string) {
+		for _, decode := range decoders {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.URL.RawQuery = query
+			r.Header.Set("Content-Type", contentType)
+			if _, err := decode(context.TODO(), r); err != nil {
+				if code := codeFrom(err); code != http.StatusBadRequest {
+					t.Fatalf("the error %q answers %d", err, code)
+				}
+			}
+		}
+		r := jsonRequest(`{"id":"1","status":"Accepted"}`)
+		r.Header.Set("If-Match", ifMatch)
+		if _, err := decodeChangeStausRequest(fuzzLimits)(context.TODO(), r); err != nil && err != ErrWeakETag &&
+			codeFrom(err) != http.StatusBadRequest {
+			t.Fatalf("the If-Match %q answers %d", ifMatch, codeFrom(err))
+		}
+	})
+}
+
+// FuzzDecodePathRequests fuzzes the decoders reading the path variables
+func FuzzDecodePathRequests(f *testing.F) {
+	f.Add("5d1e4a5c-0000-4000-8000-000000000000")
+	f.Add("")
+	f.Add("%2F..%2F")
+	decoders := []kithttp.DecodeRequestFunc{decodeGetByIDRequest, decodeGetHistoryRequest, decodeRevokeAPIKeyRequest}
+	f.Fuzz(func(t *testing.T, id string) {
+		for _, decode := range decoders {
+			r := mux.SetURLVars(httptest.NewRequest("GET", "/", nil), map[string]string{"id": url.PathEscape(id)})
+			if _, err := decode(context.TODO(), r); err != nil {
+				t.Fatalf("the id %q failed with %q", id, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"microservice_gokit_base/src/application/endpoints"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// InfoPath is the route of the instance information
+const InfoPath = "/info"
+
+// NewHTTPInfo wires the info Go kit endpoint to the HTTP transport.
+func NewHTTPInfo(infoEndpoint endpoint.Endpoint, logger log.Logger) http.Handler {
+	r := newRouter()
+	options := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+		kithttp.ServerErrorEncoder(encodeError),
+	}
+	// HTTP Get - /info
+	r.Methods("GET").Path(InfoPath).Handler(kithttp.NewServer(
+		infoEndpoint,
+		decodeInfoRequest,
+		encodeResponse,
+		options...,
+	))
+
+	return r
+}
+
+func decodeInfoRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return endpoints.InfoRequest{}, nil
+}
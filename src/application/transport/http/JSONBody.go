@@ -0,0 +1,287 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	domainSvc "microservice_gokit_base/src/domain/service"
+)
+
+// DefaultMaxBodySize is the largest JSON body read when none is configured
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultMaxImportSize is the largest imported file read when none is configured
+const DefaultMaxImportSize = 32 << 20
+
+// BodyLimits configures how the bodies of the requests are read
+type BodyLimits struct {
+	// MaxBytes is the largest JSON body accepted, bigger ones answer 413
+	MaxBytes int64
+	// MaxImportBytes is the largest imported file accepted, the imports stop with a 413 past it
+	MaxImportBytes int64
+	// DisallowUnknownFields rejects the bodies with fields the request does not have
+	DisallowUnknownFields bool
+}
+
+// DefaultBodyLimits accept JSON bodies up to DefaultMaxBodySize without unknown fields and
+// imported files up to DefaultMaxImportSize
+var DefaultBodyLimits = BodyLimits{
+	MaxBytes:              DefaultMaxBodySize,
+	MaxImportBytes:        DefaultMaxImportSize,
+	DisallowUnknownFields: true,
+}
+
+// RequestBodyError when the body of a request cannot be decoded, JSONPath points
+// to the offending value when it is known
+type RequestBodyError struct {
+	Status   int
+	Rule     string
+	JSONPath string
+	Reason   string
+}
+
+func (e RequestBodyError) Error() string {
+	if e.JSONPath != "" {
+		return fmt.Sprintf("the request was malformed: %s at %s", e.Reason, e.JSONPath)
+	}
+	return "the request was malformed: " + e.Reason
+}
+
+// Fields describes the error as the validation errors do
+func (e RequestBodyError) Fields() []domainSvc.FieldError {
+	field := e.JSONPath[strings.LastIndexAny(e.JSONPath, ".]")+1:]
+	return []domainSvc.FieldError{{Field: field, JSONPath: e.JSONPath, Rule: e.Rule, Message: e.Reason}}
+}
+
+// decodeJSON reads the JSON body of the request into v, which must be a pointer. The body
+// must be sent as application/json, fit in the limits and hold a single JSON value.
+func decodeJSON(r *http.Request, v interface{}, limits BodyLimits) error {
+	if err := checkJSONContentType(r.Header.Get("Content-Type")); err != nil {
+		return err
+	}
+	tooLarge := bodyTooLarge(limits.MaxBytes)
+	if limits.MaxBytes > 0 && r.ContentLength > limits.MaxBytes {
+		return tooLarge
+	}
+	body := io.Reader(r.Body)
+	if limits.MaxBytes > 0 {
+		body = io.LimitReader(r.Body, limits.MaxBytes+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return RequestBodyError{Status: http.StatusBadRequest, Rule: "read", Reason: "the body could not be read"}
+	}
+	if limits.MaxBytes > 0 && int64(len(data)) > limits.MaxBytes {
+		return tooLarge
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if limits.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return jsonError(err, data, reflect.TypeOf(v).Elem())
+	}
+	end := decoder.InputOffset()
+	if _, err := decoder.Token(); err != io.EOF {
+		return RequestBodyError{
+			Status: http.StatusBadRequest,
+			Rule:   "trailing",
+			Reason: "unexpected data after the JSON value ending at offset " + strconv.FormatInt(end, 10),
+		}
+	}
+	return nil
+}
+
+func bodyTooLarge(maxBytes int64) RequestBodyError {
+	return RequestBodyError{
+		Status: http.StatusRequestEntityTooLarge,
+		Rule:   "size",
+		Reason: "the body is larger than " + strconv.FormatInt(maxBytes, 10) + " bytes",
+	}
+}
+
+// limitedBody reads a streamed body failing with a 413 RequestBodyError past maxBytes
+type limitedBody struct {
+	body     io.Reader
+	maxBytes int64
+	read     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.maxBytes {
+		return 0, bodyTooLarge(b.maxBytes)
+	}
+	if remaining := b.maxBytes + 1 - b.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+	if b.read > b.maxBytes {
+		return n, bodyTooLarge(b.maxBytes)
+	}
+	return n, err
+}
+
+// checkJSONContentType accepts application/json and the +json media types
+func checkJSONContentType(contentType string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+	return RequestBodyError{
+		Status: http.StatusUnsupportedMediaType,
+		Rule:   "content_type",
+		Reason: "the body must be sent as application/json",
+	}
+}
+
+// jsonError describes a decoding error, t is the type decoded from data
+func jsonError(err error, data []byte, t reflect.Type) error {
+	bad := RequestBodyError{Status: http.StatusBadRequest}
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		bad.Rule, bad.Reason = "syntax", fmt.Sprintf("invalid JSON at offset %d", e.Offset)
+	case *json.UnmarshalTypeError:
+		bad.Rule, bad.Reason = "type", "must be "+jsonKind(e.Type)
+		bad.JSONPath = jsonPath(strings.Split(e.Field, "."))
+		if e.Field == "" {
+			bad.JSONPath = "$"
+		}
+	default:
+		switch {
+		case err == io.EOF:
+			bad.Rule, bad.Reason = "required", "the body is empty"
+		case err == io.ErrUnexpectedEOF:
+			bad.Rule, bad.Reason = "syntax", "the JSON value is truncated"
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			name, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+			bad.Rule, bad.Reason = "unknown", "the field is unknown"
+			var value interface{}
+			if json.Unmarshal(data, &value) == nil {
+				if path, ok := unknownField(value, t, nil, name); ok {
+					bad.JSONPath = jsonPath(path)
+				}
+			}
+		default:
+			bad.Rule, bad.Reason = "syntax", "invalid JSON"
+		}
+	}
+	return bad
+}
+
+// unknownField returns the path of the first field named name the type t has not
+func unknownField(value interface{}, t reflect.Type, path []string, name string) ([]string, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPath := append(append([]string{}, path...), key)
+			var fieldType reflect.Type
+			switch t.Kind() {
+			case reflect.Map:
+				fieldType = t.Elem()
+			case reflect.Struct:
+				field, ok := structField(t, key)
+				if !ok {
+					if key == name {
+						return fieldPath, true
+					}
+					continue
+				}
+				fieldType = field.Type
+			default:
+				continue
+			}
+			if found, ok := unknownField(v[key], fieldType, fieldPath, name); ok {
+				return found, true
+			}
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil, false
+		}
+		for i, item := range v {
+			if found, ok := unknownField(item, t.Elem(), append(append([]string{}, path...), strconv.Itoa(i)), name); ok {
+				return found, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// structField finds the field of a JSON key as encoding/json does, preferring the exact name
+func structField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var folded *reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			if embedded, ok := structField(field.Type, key); ok {
+				return embedded, true
+			}
+			continue
+		}
+		name, _ := jsonName(field)
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == key {
+			return field, true
+		}
+		if folded == nil && strings.EqualFold(name, key) {
+			folded = &field
+		}
+	}
+	if folded != nil {
+		return *folded, true
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonPath writes the path of a value as "$.order_items[1].quantity"
+func jsonPath(path []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, part := range path {
+		if _, err := strconv.Atoi(part); err == nil {
+			b.WriteString("[" + part + "]")
+			continue
+		}
+		b.WriteString("." + part)
+	}
+	return b.String()
+}
+
+// jsonKind names the JSON type of a Go type
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"microservice_gokit_base/src/domain/model"
+	domainSvc "microservice_gokit_base/src/domain/service"
+
+	"gotest.tools/assert"
+)
+
+func jsonRequest(body string) *http.Request {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestDecodeJSON(t *testing.T) {
+	t.Run("WHEN the body is a valid order SHOULD decode it", func(t *testing.T) {
+		var order model.Order
+		err := decodeJSON(jsonRequest(`{"restaurant_id":"r1","order_items":[{"product_code":"P1","quantity":2}]}`), &order, DefaultBodyLimits)
+		assert.NilError(t, err)
+		assert.Equal(t, order.RestaurantID, "r1")
+		assert.Equal(t, order.OrderItems[0].Quantity, int32(2))
+	})
+	t.Run("WHEN the body is not JSON SHOULD respond 415", func(t *testing.T) {
+		for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+			r := jsonRequest(`{}`)
+			r.Header.Set("Content-Type", contentType)
+			err := decodeJSON(r, &model.Order{}, DefaultBodyLimits)
+			assert.Equal(t, codeFrom(err), http.StatusUnsupportedMediaType, contentType)
+		}
+		r := jsonRequest(`{}`)
+		r.Header.Set("Content-Type", "application/merge-patch+json; charset=utf-8")
+		assert.NilError(t, decodeJSON(r, &model.Order{}, DefaultBodyLimits))
+	})
+	t.Run("WHEN the body is too large SHOULD respond 413", func(t *testing.T) {
+		limits := BodyLimits{MaxBytes: 16}
+		err := decodeJSON(jsonRequest(`{"restaurant_id":"a long restaurant"}`), &model.Order{}, limits)
+		assert.Equal(t, codeFrom(err), http.StatusRequestEntityTooLarge)
+
+		// without Content-Length the size is only known while reading
+		r := jsonRequest(`{"restaurant_id":"a long restaurant"}`)
+		r.ContentLength = -1
+		err = decodeJSON(r, &model.Order{}, limits)
+		assert.Equal(t, codeFrom(err), http.StatusRequestEntityTooLarge)
+	})
+	t.Run("WHEN the body is malformed SHOULD respond 400 with the offending JSON path", func(t *testing.T) {
+		cases := map[string]RequestBodyError{
+			`{"order_items":[{"quantity":1},{"quantity":"2"}]}`: {
+				Status: http.StatusBadRequest, Rule: "type", JSONPath: "$.order_items[1].quantity", Reason: "must be an integer",
+			},
+			`{"order_items":[{"quantity":1,"colour":"red"}]}`: {
+				Status: http.StatusBadRequest, Rule: "unknown", JSONPath: "$.order_items[0].colour", Reason: "the field is unknown",
+			},
+			`[]`: {
+				Status: http.StatusBadRequest, Rule: "type", JSONPath: "$", Reason: "must be an object",
+			},
+			`{"id":"1"} {"id":"2"}`: {
+				Status: http.StatusBadRequest, Rule: "trailing", Reason: "unexpected data after the JSON value ending at offset 10",
+			},
+			`{"id":"1"`: {
+				Status: http.StatusBadRequest, Rule: "syntax", Reason: "the JSON value is truncated",
+			},
+			`{"id":}`: {
+				Status: http.StatusBadRequest, Rule: "syntax", Reason: "invalid JSON at offset 7",
+			},
+			``: {
+				Status: http.StatusBadRequest, Rule: "required", Reason: "the body is empty",
+			},
+		}
+		for body, expected := range cases {
+			err := decodeJSON(jsonRequest(body), &model.Order{}, DefaultBodyLimits)
+			assert.DeepEqual(t, err, expected)
+		}
+	})
+	t.Run("WHEN unknown fields are allowed SHOULD ignore them", func(t *testing.T) {
+		limits := BodyLimits{MaxBytes: DefaultMaxBodySize}
+		assert.NilError(t, decodeJSON(jsonRequest(`{"colour":"red"}`), &model.Order{}, limits))
+	})
+	t.Run("WHEN the error has a path SHOULD list it on the response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		encodeError(context.TODO(), RequestBodyError{
+			Status: http.StatusBadRequest, Rule: "unknown", JSONPath: "$.filter.colour", Reason: "the field is unknown",
+		}, w)
+		assert.Equal(t, w.Code, http.StatusBadRequest)
+		var body Problem
+		assert.NilError(t, json.NewDecoder(w.Body).Decode(&body))
+		assert.DeepEqual(t, body.Errors, []domainSvc.FieldError{
+			{Field: "colour", JSONPath: "$.filter.colour", Rule: "unknown", Message: "the field is unknown"},
+		})
+	})
+	t.Run("WHEN the change status body has the version field SHOULD reject it", func(t *testing.T) {
+		_, err := decodeChangeStausRequest(DefaultBodyLimits)(context.TODO(), jsonRequest(`{"id":"1","ExpectedVersion":3}`))
+		bad, ok := err.(RequestBodyError)
+		assert.Assert(t, ok)
+		assert.Equal(t, bad.JSONPath, "$.ExpectedVersion")
+	})
+}
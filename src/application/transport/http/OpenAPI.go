@@ -0,0 +1,569 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/domain/model"
+)
+
+// paramDoc describes a path, query or header parameter of a route
+type paramDoc struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+}
+
+// routeDoc describes a route of NewHTTPOrder, the paths are relative to the base URL
+type routeDoc struct {
+	// Endpoint is the name of the endpoint of the route, giving its API key scope
+	Endpoint    string
+	Method      string
+	Path        string
+	Summary     string
+	Params      []paramDoc
+	Request     interface{}
+	RequestType []string
+	Response    interface{}
+	ContentType []string
+	Errors      []int
+	// AdminToken tells the route needs a bearer token with the admin role
+	AdminToken bool
+}
+
+var (
+	idParam     = paramDoc{Name: "id", In: "path", Description: "order id", Required: true}
+	filterQuery = []paramDoc{
+		{Name: "restaurant_id", In: "query", Description: "orders of the restaurant"},
+		{Name: "customer_id", In: "query", Description: "orders of the customer"},
+		{Name: "status", In: "query", Description: "orders in the status"},
+	}
+	formatQuery    = paramDoc{Name: "format", In: "query", Description: "file format, csv or ndjson"}
+	requestIDParam = paramDoc{Name: RequestIDHeader, In: "header", Description: "id correlating the request, generated when missing"}
+)
+
+// apiKeyScheme and bearerScheme name the security schemes of the document
+const (
+	apiKeyScheme = "apiKey"
+	bearerScheme = "bearer"
+)
+
+// serviceRoutes documents the routes of the instance, their paths are absolute
+var serviceRoutes = []routeDoc{
+	{
+		Method:   "GET",
+		Path:     InfoPath,
+		Summary:  "Build and runtime information of the instance",
+		Response: endpoints.InfoResponse{},
+	},
+	{
+		Method:      "GET",
+		Path:        SpecPath,
+		Summary:     "This OpenAPI document",
+		ContentType: []string{"application/json"},
+	},
+	{
+		Method:      "GET",
+		Path:        DocsPath,
+		Summary:     "Swagger UI page of the OpenAPI document",
+		ContentType: []string{"text/html"},
+	},
+}
+
+// adminRoutes documents every route registered by NewHTTPAdmin, the paths are relative to AdminPath
+var adminRoutes = []routeDoc{
+	{
+		Method:     "GET",
+		Path:       "log-levels",
+		Summary:    "Log level of every component",
+		Response:   endpoints.LogLevelsResponse{},
+		AdminToken: true,
+	},
+	{
+		Method:     "PUT",
+		Path:       "log-levels",
+		Summary:    "Change the log level of a component",
+		Request:    endpoints.SetLogLevelRequest{},
+		Response:   endpoints.LogLevelsResponse{},
+		Errors:     []int{http.StatusBadRequest},
+		AdminToken: true,
+	},
+	{
+		Method:     "POST",
+		Path:       "api-keys",
+		Summary:    "Issue an API key with scopes, the key is only returned once",
+		Request:    endpoints.IssueAPIKeyRequest{},
+		Response:   endpoints.IssueAPIKeyResponse{},
+		Errors:     []int{http.StatusBadRequest},
+		AdminToken: true,
+	},
+	{
+		Method:     "DELETE",
+		Path:       "api-keys/{id}",
+		Summary:    "Revoke an API key",
+		Params:     []paramDoc{{Name: "id", In: "path", Description: "API key id", Required: true}},
+		Response:   endpoints.RevokeAPIKeyResponse{},
+		Errors:     []int{http.StatusNotFound},
+		AdminToken: true,
+	},
+	{
+		Method:     "GET",
+		Path:       "vars",
+		Summary:    "Variables published with expvar, as the memory stats and the counters",
+		Response:   endpoints.VarsResponse{},
+		AdminToken: true,
+	},
+}
+
+// orderRoutes documents every route registered by NewHTTPOrder
+var orderRoutes = []routeDoc{
+	{
+		Endpoint: endpoints.CreateName,
+		Method:   "POST",
+		Path:     "orders",
+		Summary:  "Create an order",
+		Params:   []paramDoc{{Name: "Idempotency-Key", In: "header", Description: "key to safely retry the request"}},
+		Request:  model.Order{},
+		Response: endpoints.CreateResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+	{
+		Endpoint: endpoints.CreateBatchName,
+		Method:   "POST",
+		Path:     "orders/batch",
+		Summary:  "Create many orders, validating each one on its own",
+		Request:  []model.Order{},
+		Response: endpoints.CreateBatchResponse{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		Endpoint:    endpoints.ImportName,
+		Method:      "POST",
+		Path:        "orders/import",
+		Summary:     "Import orders from a CSV or NDJSON file",
+		Params:      []paramDoc{formatQuery},
+		RequestType: []string{"text/csv", "application/x-ndjson"},
+		Response:    endpoints.ImportResponse{},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Endpoint: endpoints.CountName,
+		Method:   "GET",
+		Path:     "orders/count",
+		Summary:  "Count the orders",
+		Response: endpoints.CountResponse{},
+	},
+	{
+		Endpoint: endpoints.GetByIDName,
+		Method:   "GET",
+		Path:     "orders/id/{id}",
+		Summary:  "Get an order, its version is returned on the ETag header",
+		Params:   []paramDoc{idParam},
+		Response: endpoints.GetByIDResponse{},
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Endpoint: endpoints.GetHistoryName,
+		Method:   "GET",
+		Path:     "orders/{id}/history",
+		Summary:  "Get the status history of an order",
+		Params:   []paramDoc{idParam},
+		Response: endpoints.GetHistoryResponse{},
+		Errors:   []int{http.StatusNotFound},
+	},
+	{
+		Endpoint:    endpoints.ExportName,
+		Method:      "GET",
+		Path:        "orders/export",
+		Summary:     "Stream the orders as CSV or NDJSON",
+		Params:      append([]paramDoc{formatQuery}, filterQuery...),
+		ContentType: []string{"text/csv", "application/x-ndjson"},
+		Errors:      []int{http.StatusBadRequest},
+	},
+	{
+		Endpoint: endpoints.GetAllName,
+		Method:   "GET",
+		Path:     "orders",
+		Summary:  "List the orders, all of them or a page when size is given",
+		Params: append([]paramDoc{
+			{Name: "page", In: "query", Description: "page number starting at 0"},
+			{Name: "size", In: "query", Description: "orders of the page"},
+		}, filterQuery...),
+		Response: endpoints.GetlAllResponse{},
+	},
+	{
+		Endpoint: endpoints.ChangeStatusName,
+		Method:   "PUT",
+		Path:     "orders/status",
+		Summary:  "Change the status of an order",
+		Params:   []paramDoc{{Name: "If-Match", In: "header", Description: "ETag of the expected order version"}},
+		Request:  endpoints.ChangeStatusRequest{},
+		Response: endpoints.ChangeStatusResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusPreconditionFailed},
+	},
+	{
+		Endpoint: endpoints.ChangeStatusBulkName,
+		Method:   "PUT",
+		Path:     "orders/status/bulk",
+		Summary:  "Change the status of many orders given by ids or by restaurant and status",
+		Request:  endpoints.ChangeStatusBulkRequest{},
+		Response: endpoints.ChangeStatusBulkResponse{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+}
+
+// SpecPath and DocsPath are the routes of the OpenAPI document and of its Swagger UI page
+const (
+	SpecPath = "/openapi.json"
+	DocsPath = "/docs"
+)
+
+// NewHTTPDocs serves the OpenAPI document of the routes of NewHTTPOrder at specPath
+// and its Swagger UI page at docsPath.
+func NewHTTPDocs(baseURL string, specPath string, docsPath string, assetsDir string) http.Handler {
+	r := newRouter()
+	r.Methods("GET").Path(specPath).Handler(NewOpenAPIHandler(baseURL))
+	assetsURL := SwaggerUIAssetsURL
+	if assetsDir != "" {
+		assetsURL = docsPath + "/assets"
+		r.Methods("GET").PathPrefix(assetsURL + "/").Handler(NewDocsAssetsHandler(assetsURL, assetsDir))
+	}
+	r.Methods("GET").Path(docsPath).Handler(NewDocsHandler(specPath, assetsURL))
+	return r
+}
+
+// NewOpenAPIHandler serves the OpenAPI document of the routes of NewHTTPOrder
+func NewOpenAPIHandler(baseURL string) http.Handler {
+	document, err := json.Marshal(OpenAPIDocument(baseURL))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			encodeError(r.Context(), err, w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(document)
+	})
+}
+
+// NewDocsHandler serves a Swagger UI page of the OpenAPI document at specURL, loading
+// the Swagger UI files from assetsURL
+func NewDocsHandler(specURL string, assetsURL string) http.Handler {
+	page := strings.Replace(swaggerUIPage, "{{SPEC_URL}}", strconv.Quote(specURL), 1)
+	page = strings.Replace(page, "{{ASSETS_URL}}", assetsURL, -1)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+}
+
+// NewDocsAssetsHandler serves the Swagger UI files of the page from a local copy of swagger-ui-dist
+func NewDocsAssetsHandler(assetsURL string, assetsDir string) http.Handler {
+	files := http.StripPrefix(assetsURL+"/", http.FileServer(http.Dir(assetsDir)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !swaggerUIAssets[strings.TrimPrefix(r.URL.Path, assetsURL+"/")] {
+			encodeError(r.Context(), ErrRouteNotFound, w)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// OpenAPIDocument generates the OpenAPI 3 document of the routes, the schemas are
+// taken from the JSON encoding of the request and response types
+func OpenAPIDocument(baseURL string) map[string]interface{} {
+	schemas := schemaBuilder{components: map[string]interface{}{}}
+	requestSchemas := schemaBuilder{components: schemas.components, request: true}
+	problemSchema := schemas.schemaOf(reflect.TypeOf(Problem{}))
+
+	paths := map[string]interface{}{}
+	routes := append(prefixedRoutes(baseURL, orderRoutes), serviceRoutes...)
+	for _, route := range append(routes, prefixedRoutes(AdminPath, adminRoutes)...) {
+		operation := map[string]interface{}{
+			"summary":     route.Summary,
+			"operationId": operationID(route.Method, strings.TrimPrefix(route.Path, baseURL)),
+		}
+		var params []interface{}
+		for _, p := range append([]paramDoc{requestIDParam}, route.Params...) {
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Description,
+				"required":    p.Required,
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(requestSchemas.schemaOf(reflect.TypeOf(route.Request))),
+			}
+		} else if len(route.RequestType) > 0 {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  fileContent(route.RequestType),
+			}
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		if route.Response != nil {
+			ok["content"] = jsonContent(schemas.schemaOf(reflect.TypeOf(route.Response)))
+		} else if len(route.ContentType) > 0 {
+			ok["content"] = fileContent(route.ContentType)
+		}
+		responses := map[string]interface{}{"200": ok}
+		codes := append(route.Errors, http.StatusInternalServerError)
+		if route.Request != nil {
+			codes = append(codes, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+		}
+		if scope, ok := endpoints.APIKeyScopes[route.Endpoint]; ok {
+			operation["security"] = []interface{}{map[string]interface{}{apiKeyScheme: []string{scope}}}
+			codes = append(codes, http.StatusUnauthorized, http.StatusForbidden)
+		}
+		if route.AdminToken {
+			operation["security"] = []interface{}{map[string]interface{}{bearerScheme: []string{}}}
+			codes = append(codes, http.StatusUnauthorized, http.StatusForbidden)
+		}
+		for _, code := range codes {
+			responses[strconv.Itoa(code)] = map[string]interface{}{
+				"description": http.StatusText(code),
+				"content":     problemContent(problemSchema),
+			}
+		}
+		operation["responses"] = responses
+
+		item, _ := paths[route.Path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Orders microservice",
+			"version": strings.Trim(baseURL, "/"),
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				apiKeyScheme: map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        APIKeyHeader,
+					"description": "API key issued on /admin/api-keys, required when UP_API_KEYS_REQUIRED is set",
+				},
+				bearerScheme: map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "token signed with UP_SECURITY_SECRET, the admin routes need the admin role",
+				},
+			},
+		},
+	}
+}
+
+// prefixedRoutes returns the routes with the base URL before their paths
+func prefixedRoutes(baseURL string, routes []routeDoc) []routeDoc {
+	prefixed := make([]routeDoc, len(routes))
+	for i, route := range routes {
+		route.Path = baseURL + route.Path
+		prefixed[i] = route
+	}
+	return prefixed
+}
+
+func operationID(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.Split(path, "/") {
+		part = strings.Trim(part, "{}")
+		if part != "" {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return id
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+func problemContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		ProblemContentType: map[string]interface{}{"schema": schema},
+	}
+}
+
+func fileContent(mediaTypes []string) map[string]interface{} {
+	content := map[string]interface{}{}
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = map[string]interface{}{
+			"schema": map[string]interface{}{"type": "string"},
+		}
+	}
+	return content
+}
+
+// schemaBuilder generates JSON schemas of Go types, named structs are added to the components.
+// The response schemas require the fields always encoded, the request ones leave out the
+// fields tagged openapi:"readonly" and require the ones validated as nonzero, their components
+// being named with a Request suffix.
+type schemaBuilder struct {
+	components map[string]interface{}
+	request    bool
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (b *schemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		return b.structRef(t)
+	}
+	return map[string]interface{}{}
+}
+
+// structRef registers the schema of a struct on the components and returns its reference
+func (b *schemaBuilder) structRef(t reflect.Type) map[string]interface{} {
+	name := t.Name()
+	if b.request && !strings.HasSuffix(name, "Request") {
+		name += "Request"
+	}
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, ok := b.components[name]; ok {
+		return ref
+	}
+	// registered before the fields so recursive types end
+	schema := map[string]interface{}{"type": "object"}
+	b.components[name] = schema
+
+	properties := map[string]interface{}{}
+	required := b.addFields(t, properties, nil)
+	schema["properties"] = properties
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return ref
+}
+
+// addFields adds the schemas of the fields of a struct to the properties, inlining the
+// embedded structs as the JSON encoding does, and returns the required names
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required []string) []string {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			required = b.addFields(field.Type, properties, required)
+			continue
+		}
+		if field.PkgPath != "" || field.Type.Kind() == reflect.Interface {
+			continue
+		}
+		name, omitempty := jsonName(field)
+		if name == "-" || (b.request && field.Tag.Get("openapi") == "readonly") {
+			continue
+		}
+		properties[name] = b.schemaOf(field.Type)
+		if (b.request && validatedNonZero(field)) || (!b.request && !omitempty) {
+			required = append(required, name)
+		}
+	}
+	return required
+}
+
+// validatedNonZero tells if the validate tag of a field rejects its zero value
+func validatedNonZero(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule = strings.TrimSpace(rule); rule == "nonzero" || rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonName returns the name of a field on its JSON encoding
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "-", false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitempty := false
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty
+}
+
+// SwaggerUIVersion is the release of swagger-ui-dist the docs page is written for
+const SwaggerUIVersion = "5.17.14"
+
+// SwaggerUIAssetsURL is the pinned release of the Swagger UI files loaded by the page when
+// no local copy is served, the Docker image serves the copy it is built with
+const SwaggerUIAssetsURL = "https://unpkg.com/swagger-ui-dist@" + SwaggerUIVersion
+
+// swaggerUIAssets are the files of swagger-ui-dist used by the page
+var swaggerUIAssets = map[string]bool{"swagger-ui.css": true, "swagger-ui-bundle.js": true}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Orders microservice - API docs</title>
+  <link rel="stylesheet" href="{{ASSETS_URL}}/swagger-ui.css" crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{ASSETS_URL}}/swagger-ui-bundle.js" crossorigin="anonymous"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({ url: {{SPEC_URL}}, dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"microservice_gokit_base/src/application/endpoints"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"gotest.tools/assert"
+)
+
+const testBaseURL = "/api/v1/"
+
+// routerOperations walks every router mounted by main
+func routerOperations(t *testing.T) map[string]bool {
+	routers := []http.Handler{
+		NewHTTPOrder(endpoints.MakeOrderEndpoints(nil), log.NewNopLogger(), testBaseURL),
+		NewHTTPInfo(endpoints.MakeInfoEndpoint(endpoints.InstanceInfo{}), log.NewNopLogger()),
+		NewHTTPAdmin(endpoints.MakeAdminEndpoints(nil, nil, nil), log.NewNopLogger(), AdminPath),
+		NewHTTPDocs(testBaseURL, SpecPath, DocsPath, ""),
+	}
+	operations := map[string]bool{}
+	for _, router := range routers {
+		err := router.(*mux.Router).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				return err
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				return err
+			}
+			for _, method := range methods {
+				operations[strings.ToLower(method)+" "+path] = true
+			}
+			return nil
+		})
+		assert.NilError(t, err)
+	}
+	return operations
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	t.Run("WHEN the document is generated SHOULD describe exactly the routes of the router", func(t *testing.T) {
+		document := OpenAPIDocument(testBaseURL)
+		documented := map[string]bool{}
+		for path, item := range document["paths"].(map[string]interface{}) {
+			for method := range item.(map[string]interface{}) {
+				documented[method+" "+path] = true
+			}
+		}
+		assert.DeepEqual(t, documented, routerOperations(t))
+	})
+	t.Run("WHEN the document is generated SHOULD define every referenced schema", func(t *testing.T) {
+		document := OpenAPIDocument(testBaseURL)
+		raw, err := json.Marshal(document)
+		assert.NilError(t, err)
+		schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		for _, part := range strings.Split(string(raw), `"$ref":"#/components/schemas/`)[1:] {
+			name := part[:strings.Index(part, `"`)]
+			_, ok := schemas[name]
+			assert.Assert(t, ok, "schema %s is not defined", name)
+		}
+	})
+	t.Run("WHEN the order schema is generated SHOULD use the json names and skip the errors", func(t *testing.T) {
+		schemas := OpenAPIDocument(testBaseURL)["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		order := schemas["Order"].(map[string]interface{})["properties"].(map[string]interface{})
+		_, ok := order["order_items"]
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, order["created_on"], map[string]interface{}{"type": "integer", "format": "int64"})
+		create := schemas["CreateResponse"].(map[string]interface{})["properties"].(map[string]interface{})
+		_, ok = create["error"]
+		assert.Assert(t, !ok)
+	})
+	t.Run("WHEN an order is sent SHOULD require the validated fields and leave out the ones of the service", func(t *testing.T) {
+		schemas := OpenAPIDocument(testBaseURL)["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		request := schemas["OrderRequest"].(map[string]interface{})
+		assert.DeepEqual(t, request["required"], []string{"restaurant_id", "order_items"})
+		for _, field := range []string{"id", "status", "created_on", "status_history", "version"} {
+			_, ok := request["properties"].(map[string]interface{})[field]
+			assert.Assert(t, !ok, field)
+		}
+		item := schemas["OrderItemRequest"].(map[string]interface{})
+		assert.DeepEqual(t, item["required"], []string{"product_code"})
+
+		response := schemas["Order"].(map[string]interface{})
+		assert.DeepEqual(t, response["required"], []string{"customer_id", "status", "restaurant_id", "version"})
+		create := OpenAPIDocument(testBaseURL)["paths"].(map[string]interface{})[testBaseURL+"orders"].(map[string]interface{})["post"].(map[string]interface{})
+		body := create["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"]
+		assert.DeepEqual(t, body, map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/OrderRequest"}})
+	})
+	t.Run("WHEN the errors are documented SHOULD describe them as problems", func(t *testing.T) {
+		document := OpenAPIDocument(testBaseURL)
+		get := document["paths"].(map[string]interface{})[testBaseURL+"orders/id/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+		notFound := get["responses"].(map[string]interface{})["404"].(map[string]interface{})["content"].(map[string]interface{})
+		assert.DeepEqual(t, notFound[ProblemContentType], map[string]interface{}{
+			"schema": map[string]interface{}{"$ref": "#/components/schemas/Problem"},
+		})
+	})
+}
+
+func TestOpenAPIHandlers(t *testing.T) {
+	t.Run("WHEN the spec is requested SHOULD serve it as JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewOpenAPIHandler(testBaseURL).ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+		assert.Equal(t, w.Code, http.StatusOK)
+		var document map[string]interface{}
+		assert.NilError(t, json.NewDecoder(w.Body).Decode(&document))
+		assert.Equal(t, document["openapi"], "3.0.3")
+	})
+	t.Run("WHEN the docs are requested SHOULD serve the page pointing to the spec", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewDocsHandler("/openapi.json", SwaggerUIAssetsURL).ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Assert(t, strings.Contains(w.Body.String(), `url: "/openapi.json"`))
+		assert.Assert(t, strings.Contains(w.Body.String(), "swagger-ui-dist@"+SwaggerUIVersion+"/swagger-ui-bundle.js"))
+	})
+	t.Run("WHEN a local copy of the assets is given SHOULD serve the page and its files from it", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "swagger-ui")
+		assert.NilError(t, err)
+		defer os.RemoveAll(dir)
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "swagger-ui-bundle.js"), []byte("bundle"), 0600))
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "package.json"), []byte("{}"), 0600))
+		handler := NewHTTPDocs(testBaseURL, "/openapi.json", "/docs", dir)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+		assert.Assert(t, strings.Contains(w.Body.String(), `src="/docs/assets/swagger-ui-bundle.js"`))
+		assert.Assert(t, !strings.Contains(w.Body.String(), "unpkg.com"))
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/docs/assets/swagger-ui-bundle.js", nil))
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Body.String(), "bundle")
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/docs/assets/package.json", nil))
+		assert.Equal(t, w.Code, http.StatusNotFound)
+	})
+}
@@ -2,33 +2,69 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"microservice_gokit_base/src/application/codec"
 	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/domain/utils"
 
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 )
 
+// exportFlushSize is the number of orders written between flushes of the export
+const exportFlushSize = 500
+
+// OrderOption configures the HTTP transport of the Order endpoints
+type OrderOption func(*orderTransport)
+
+type orderTransport struct {
+	limits BodyLimits
+}
+
+// WithBodyLimits sets how the JSON bodies are read, DefaultBodyLimits when not given
+func WithBodyLimits(limits BodyLimits) OrderOption {
+	return func(t *orderTransport) {
+		t.limits = limits
+	}
+}
+
 // NewHTTPOrder wires Go kit endpoints to the HTTP transport.
 func NewHTTPOrder(
 	svcEndpoints endpoints.IOrderEndpoints,
-	logger log.Logger, baseURL string,
+	logger log.Logger, baseURL string, orderOptions ...OrderOption,
 ) http.Handler {
+	t := &orderTransport{limits: DefaultBodyLimits}
+	for _, option := range orderOptions {
+		option(t)
+	}
 	// set-up router and initialize http endpoints
-	r := mux.NewRouter()
+	r := newRouter()
 	options := []kithttp.ServerOption{
 		kithttp.ServerErrorLogger(logger),
 		kithttp.ServerErrorEncoder(encodeError),
+		kithttp.ServerBefore(apiKeyToContext, bearerTokenToContext, rateLimitToContext),
+		kithttp.ServerAfter(rateLimitHeaders),
 	}
 	// HTTP Post - /orders
 	r.Methods("POST").Path(baseURL + "orders").Handler(kithttp.NewServer(
 		svcEndpoints.CreateEndpoint(),
-		decodeCreateRequest(),
+		decodeCreateRequest(t.limits),
+		encodeResponse,
+		append(options, kithttp.ServerBefore(idempotencyKeyToContext))...,
+	))
+	// HTTP Post - /orders/batch
+	r.Methods("POST").Path(baseURL + "orders/batch").Handler(kithttp.NewServer(
+		svcEndpoints.CreateBatchEndpoint(),
+		decodeCreateBatchRequest(t.limits),
 		encodeResponse,
 		options...,
 	))
@@ -43,21 +79,37 @@ func NewHTTPOrder(
 	r.Methods("GET").Path(baseURL + "orders/id/{id}").Handler(kithttp.NewServer(
 		svcEndpoints.GetByIDEndpoint(),
 		decodeGetByIDRequest,
+		encodeGetByIDResponse,
+		options...,
+	))
+
+	// HTTP Get - /orders/{id}/history
+	r.Methods("GET").Path(baseURL + "orders/{id}/history").Handler(kithttp.NewServer(
+		svcEndpoints.GetHistoryEndpoint(),
+		decodeGetHistoryRequest,
 		encodeResponse,
 		options...,
 	))
 
-	// HTTP Get - /orders
-	r.Methods("GET").Path(baseURL + "orders").Handler(kithttp.NewServer(
-		svcEndpoints.GetAllEndpoint(),
-		decodeGetAll,
+	// HTTP Get - /orders/export
+	r.Methods("GET").Path(baseURL + "orders/export").Handler(kithttp.NewServer(
+		svcEndpoints.ExportEndpoint(),
+		decodeExportRequest,
+		encodeExportResponse(logger),
+		options...,
+	))
+
+	// HTTP Post - /orders/import
+	r.Methods("POST").Path(baseURL + "orders/import").Handler(kithttp.NewServer(
+		svcEndpoints.ImportEndpoint(),
+		decodeImportRequest(t.limits),
 		encodeResponse,
 		options...,
 	))
 
 	// HTTP Get - /orders
-	r.Methods("GET").Path(baseURL + "orders/saludo").Handler(kithttp.NewServer(
-		svcEndpoints.SaludoEndpoint(),
+	r.Methods("GET").Path(baseURL + "orders").Handler(kithttp.NewServer(
+		svcEndpoints.GetAllEndpoint(),
 		decodeGetAll,
 		encodeResponse,
 		options...,
@@ -66,7 +118,15 @@ func NewHTTPOrder(
 	// HTTP Put - /orders/status
 	r.Methods("PUT").Path(baseURL + "orders/status").Handler(kithttp.NewServer(
 		svcEndpoints.ChangeStatusEndpoint(),
-		decodeChangeStausRequest,
+		decodeChangeStausRequest(t.limits),
+		encodeResponse,
+		options...,
+	))
+
+	// HTTP Put - /orders/status/bulk
+	r.Methods("PUT").Path(baseURL + "orders/status/bulk").Handler(kithttp.NewServer(
+		svcEndpoints.ChangeStatusBulkEndpoint(),
+		decodeChangeStatusBulkRequest(t.limits),
 		encodeResponse,
 		options...,
 	))
@@ -74,16 +134,33 @@ func NewHTTPOrder(
 	return r
 }
 
-func decodeCreateRequest() kithttp.DecodeRequestFunc {
+func decodeCreateRequest(limits BodyLimits) kithttp.DecodeRequestFunc {
 	return func(_ context.Context, r *http.Request) (request interface{}, err error) {
 		var req endpoints.CreateRequest
-		if e := json.NewDecoder(r.Body).Decode(&req.Order); e != nil {
-			return nil, ErrBadRequest(e)
+		if e := decodeJSON(r, &req.Order, limits); e != nil {
+			return nil, e
 		}
 		return req, nil
 	}
 }
 
+func decodeCreateBatchRequest(limits BodyLimits) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (request interface{}, err error) {
+		var req endpoints.CreateBatchRequest
+		if e := decodeJSON(r, &req.Orders, limits); e != nil {
+			return nil, e
+		}
+		return req, nil
+	}
+}
+
+func idempotencyKeyToContext(ctx context.Context, r *http.Request) context.Context {
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		return endpoints.ContextWithIdempotencyKey(ctx, key)
+	}
+	return ctx
+}
+
 func decodeGetByIDRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
 	vars := mux.Vars(r)
 	id, ok := vars["id"]
@@ -93,22 +170,165 @@ func decodeGetByIDRequest(_ context.Context, r *http.Request) (request interface
 	return endpoints.GetByIDRequest{ID: id}, nil
 }
 
-func decodeChangeStausRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
-	var req endpoints.ChangeStatusRequest
-	if e := json.NewDecoder(r.Body).Decode(&req); e != nil {
-		return nil, ErrBadRequest(e)
+func decodeGetHistoryRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, ErrBadRouting(errors.New("No id parameter found"))
+	}
+	return endpoints.GetHistoryRequest{ID: id}, nil
+}
+
+func encodeGetByIDResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if res, ok := response.(endpoints.GetByIDResponse); ok && res.Err == nil {
+		w.Header().Set("ETag", formatETag(res.Order.Version))
+	}
+	return encodeResponse(ctx, w, response)
+}
+
+func decodeChangeStausRequest(limits BodyLimits) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (request interface{}, err error) {
+		var req endpoints.ChangeStatusRequest
+		if e := decodeJSON(r, &req, limits); e != nil {
+			return nil, e
+		}
+		version, check, e := parseIfMatch(r.Header.Get("If-Match"))
+		if e == ErrWeakETag {
+			return nil, e
+		}
+		if e != nil {
+			return nil, ErrBadRequest(e)
+		}
+		req.ExpectedVersion, req.CheckVersion = version, check
+		return req, nil
+	}
+}
+
+func decodeChangeStatusBulkRequest(limits BodyLimits) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (request interface{}, err error) {
+		var req endpoints.ChangeStatusBulkRequest
+		if e := decodeJSON(r, &req, limits); e != nil {
+			return nil, e
+		}
+		return req, nil
 	}
-	return req, nil
 }
 
 func decodeGetAll(_ context.Context, r *http.Request) (request interface{}, err error) {
 	page, _ := strconv.Atoi(r.FormValue("page"))
 	size, _ := strconv.Atoi(r.FormValue("size"))
 
+	filter := decodeFilter(r)
+
 	if size != 0 {
-		return endpoints.GetAllRequest{Page: int64(page), Size: int64(size)}, nil
+		return endpoints.GetAllRequest{Page: int64(page), Size: int64(size), Filter: filter}, nil
+	}
+	return endpoints.GetAllRequest{Page: 0, Size: 0, Filter: filter}, nil
+}
+
+func decodeExportRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	format := r.FormValue("format")
+	if format == "" {
+		format = codec.FormatNDJSON
+	}
+	if format != codec.FormatCSV && format != codec.FormatNDJSON {
+		return nil, ErrBadRequest(codec.ErrUnknownFormat)
+	}
+	return endpoints.ExportRequest{Format: format, Filter: decodeFilter(r)}, nil
+}
+
+func decodeImportRequest(limits BodyLimits) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (request interface{}, err error) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = formatFromContentType(r.Header.Get("Content-Type"))
+		}
+		if format != codec.FormatCSV && format != codec.FormatNDJSON {
+			return nil, ErrBadRequest(codec.ErrUnknownFormat)
+		}
+		file := io.Reader(r.Body)
+		if limits.MaxImportBytes > 0 {
+			if r.ContentLength > limits.MaxImportBytes {
+				return nil, bodyTooLarge(limits.MaxImportBytes)
+			}
+			file = &limitedBody{body: r.Body, maxBytes: limits.MaxImportBytes}
+		}
+		return endpoints.ImportRequest{Format: format, File: file}, nil
+	}
+}
+
+// formatFromContentType returns the file format of a media type, empty when unknown
+func formatFromContentType(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "text/csv"):
+		return codec.FormatCSV
+	case strings.HasPrefix(contentType, "application/x-ndjson"):
+		return codec.FormatNDJSON
+	}
+	return ""
+}
+
+// decodeFilter reads the order filters shared by the listing and the export
+func decodeFilter(r *http.Request) repository.OrderFilter {
+	return repository.OrderFilter{
+		RestaurantID: r.FormValue("restaurant_id"),
+		CustomerID:   r.FormValue("customer_id"),
+		Status:       r.FormValue("status"),
+	}
+}
+
+// encodeExportResponse streams the orders while they are read from the repository, a failure
+// once the stream has started aborts the connection so the client does not take the file as complete
+func encodeExportResponse(logger log.Logger) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		res := response.(endpoints.ExportResponse)
+		if res.Err != nil {
+			encodeError(ctx, res.Err, w)
+			return nil
+		}
+		defer res.Orders.Close(ctx)
+
+		writer, err := codec.NewWriter(res.Format, w)
+		if err != nil {
+			encodeError(ctx, ErrBadRequest(err), w)
+			return nil
+		}
+		w.Header().Set("Content-Type", codec.ContentType(res.Format))
+		w.Header().Set("Content-Disposition", "attachment; filename=\"orders."+res.Format+"\"")
+		if err := streamOrders(ctx, res.Orders, writer, w); err != nil {
+			level.Error(utils.LoggerFromContext(ctx, logger)).Log("msg", "export aborted", "err", err)
+			panic(http.ErrAbortHandler)
+		}
+		return nil
+	}
+}
+
+// streamOrders writes the orders of the iterator, flushing them to the client every exportFlushSize orders
+func streamOrders(ctx context.Context, orders repository.IOrderIterator, writer codec.OrderWriter, w http.ResponseWriter) error {
+	flusher, _ := w.(http.Flusher)
+	written := 0
+	for orders.Next(ctx) {
+		var order model.Order
+		if err := orders.Decode(&order); err != nil {
+			return err
+		}
+		if err := writer.Write(order); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushSize == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := orders.Err(); err != nil {
+		return err
 	}
-	return endpoints.GetAllRequest{Page: 0, Size: 0}, nil
+	return writer.Flush()
 }
 
 func decodeCount(_ context.Context, r *http.Request) (request interface{}, err error) {
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/domain/model"
+
+	"github.com/go-kit/kit/log"
+	"gotest.tools/assert"
+)
+
+// failingIterator returns its orders and then fails with err
+type failingIterator struct {
+	orders []model.Order
+	err    error
+}
+
+func (it *failingIterator) Next(ctx context.Context) bool { return len(it.orders) > 0 }
+
+func (it *failingIterator) Decode(order *model.Order) error {
+	*order, it.orders = it.orders[0], it.orders[1:]
+	return nil
+}
+
+func (it *failingIterator) Err() error { return it.err }
+
+func (it *failingIterator) Close(ctx context.Context) error { return nil }
+
+func TestEncodeExportResponse(t *testing.T) {
+	encode := encodeExportResponse(log.NewNopLogger())
+
+	t.Run("WHEN the orders are read SHOULD stream them", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		orders := &failingIterator{orders: []model.Order{{ID: "1"}, {ID: "2"}}}
+		assert.NilError(t, encode(context.TODO(), w, endpoints.ExportResponse{Format: "ndjson", Orders: orders}))
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Header().Get("Content-Type"), "application/x-ndjson")
+	})
+	t.Run("WHEN the iteration fails SHOULD abort the response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		orders := &failingIterator{orders: []model.Order{{ID: "1"}}, err: context.DeadlineExceeded}
+		defer func() {
+			assert.Equal(t, recover(), http.ErrAbortHandler)
+		}()
+		encode(context.TODO(), w, endpoints.ExportResponse{Format: "csv", Orders: orders})
+		t.Fatal("the export was not aborted")
+	})
+	t.Run("WHEN the export is rejected SHOULD answer the problem", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		assert.NilError(t, encode(context.TODO(), w, endpoints.ExportResponse{Err: errors.New("auth: no token")}))
+		assert.Equal(t, w.Code, http.StatusUnauthorized)
+	})
+}
+
+func TestImportBodyLimit(t *testing.T) {
+	limits := DefaultBodyLimits
+	limits.MaxImportBytes = 64
+	router := NewHTTPOrder(endpoints.MakeOrderEndpoints(nil), log.NewNopLogger(), testBaseURL, WithBodyLimits(limits))
+	file := strings.Repeat(`{"restaurant_id":"r1"}`+"\n", 10)
+	serve := func(contentLength int64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", testBaseURL+"orders/import", strings.NewReader(file))
+		r.Header.Set("Content-Type", "application/x-ndjson")
+		r.ContentLength = contentLength
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("WHEN the file is declared larger than the limit SHOULD respond 413", func(t *testing.T) {
+		w := serve(int64(len(file)))
+		assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+	})
+	t.Run("WHEN the streamed file goes past the limit SHOULD stop it with a 413", func(t *testing.T) {
+		w := serve(-1)
+		assert.Equal(t, w.Code, http.StatusRequestEntityTooLarge)
+		assert.Assert(t, strings.Contains(decodeProblem(t, w).Detail, "larger than 64 bytes"))
+	})
+}
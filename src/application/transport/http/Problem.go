@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"microservice_gokit_base/src/application/endpoints"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	domainSvc "microservice_gokit_base/src/domain/service"
+	"microservice_gokit_base/src/domain/utils"
+)
+
+// ProblemContentType is the media type of the error responses, RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBlank is the problem type of the errors described by their status alone
+const problemTypeBlank = "about:blank"
+
+var (
+	// ErrRouteNotFound when no route matches the path of the request
+	ErrRouteNotFound = errors.New("no route matches the path of the request")
+	// ErrMethodNotAllowed when the route of the path is not registered with the method of the request
+	ErrMethodNotAllowed = errors.New("the route does not accept the method of the request")
+)
+
+// Problem is the RFC 7807 body of the failed responses, extended with the request id,
+// the current status of the order on a status conflict and the field errors
+type Problem struct {
+	Type          string                 `json:"type"`
+	Title         string                 `json:"title"`
+	Status        int                    `json:"status"`
+	Detail        string                 `json:"detail,omitempty"`
+	Instance      string                 `json:"instance,omitempty"`
+	RequestID     string                 `json:"request_id,omitempty"`
+	CurrentStatus string                 `json:"current_status,omitempty"`
+	Errors        []domainSvc.FieldError `json:"errors,omitempty"`
+}
+
+// problemKind is the type and title of the errors that need more than their status to be told apart
+type problemKind struct {
+	Type  string
+	Title string
+}
+
+var (
+	problemInvalidBody        = problemKind{"urn:problem-type:invalid-body", "The request body is not valid"}
+	problemValidation         = problemKind{"urn:problem-type:validation-error", "The request is not valid"}
+	problemPolicy             = problemKind{"urn:problem-type:policy-violation", "The order breaks a policy"}
+	problemStatusConflict     = problemKind{"urn:problem-type:status-conflict", "The order is not in the expected status"}
+	problemVersionConflict    = problemKind{"urn:problem-type:version-conflict", "The order changed since it was read"}
+	problemIdempotencyReused  = problemKind{"urn:problem-type:idempotency-key-reused", "The idempotency key was used with another request"}
+	problemIdempotencyPending = problemKind{"urn:problem-type:idempotency-in-progress", "The idempotent request is in progress"}
+	problemRateLimited        = problemKind{"urn:problem-type:rate-limited", "Too many requests"}
+	problemScopeMissing       = problemKind{"urn:problem-type:scope-missing", "The caller lacks the scope of the endpoint"}
+	problemOverloaded         = problemKind{"urn:problem-type:overloaded", "The service is overloaded"}
+	problemTimeout            = problemKind{"urn:problem-type:timeout", "The request took too long"}
+	problemInternal           = problemKind{"urn:problem-type:internal-error", "Internal error"}
+	problemKindsByError       = map[error]problemKind{
+		domainRepo.ErrVersionConflict:      problemVersionConflict,
+		endpoints.ErrIdempotencyKeyReused:  problemIdempotencyReused,
+		endpoints.ErrIdempotencyInProgress: problemIdempotencyPending,
+		endpoints.ErrScopeMissing:          problemScopeMissing,
+		endpoints.ErrShed:                  problemOverloaded,
+		endpoints.ErrTimeout:               problemTimeout,
+		endpoints.ErrPanic:                 problemInternal,
+	}
+)
+
+// newProblem describes the error answered with the status code, the personal data of the detail is masked
+func newProblem(ctx context.Context, err error, code int) Problem {
+	kind := problemKindOf(err)
+	if kind.Type == "" {
+		kind = problemKind{Type: problemTypeBlank, Title: http.StatusText(code)}
+	}
+	problem := Problem{
+		Type:      kind.Type,
+		Title:     kind.Title,
+		Status:    code,
+		Detail:    errorRedactor.String(err.Error()),
+		Instance:  instanceFromContext(ctx),
+		RequestID: utils.RequestIDFromContext(ctx),
+	}
+	switch e := err.(type) {
+	case domainRepo.StatusConflictError:
+		problem.CurrentStatus = e.Actual
+	case domainSvc.ValidationError:
+		problem.Errors = e.Fields
+	case RequestBodyError:
+		if e.JSONPath != "" {
+			problem.Errors = e.Fields()
+		}
+	}
+	return problem
+}
+
+func problemKindOf(err error) problemKind {
+	switch err.(type) {
+	case RequestBodyError:
+		return problemInvalidBody
+	case domainSvc.ValidationError:
+		return problemValidation
+	case domainSvc.PolicyError:
+		return problemPolicy
+	case domainRepo.StatusConflictError:
+		return problemStatusConflict
+	case endpoints.RateLimitedError:
+		return problemRateLimited
+	}
+	for known, kind := range problemKindsByError {
+		if err == known {
+			return kind
+		}
+	}
+	return problemKind{}
+}
+
+type instanceContextKey struct{}
+
+// instanceMiddleware stores the path of the request as the instance of its problems
+func instanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(contextWithInstance(r.Context(), r)))
+	})
+}
+
+func contextWithInstance(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, instanceContextKey{}, r.URL.Path)
+}
+
+func instanceFromContext(ctx context.Context) string {
+	instance, _ := ctx.Value(instanceContextKey{}).(string)
+	return instance
+}
+
+// NewNotFound answers the requests no handler is mounted for with a 404 problem
+func NewNotFound() http.Handler {
+	return problemHandler(ErrRouteNotFound)
+}
+
+// problemHandler answers every request with the problem of err, giving them a request id
+// since the middlewares of a router are not applied when none of its routes match
+func problemHandler(err error) http.Handler {
+	return requestIDMiddleware(utils.NewUUIDGenerator())(instanceMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encodeError(r.Context(), err, w)
+		})))
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	domainSvc "microservice_gokit_base/src/domain/service"
+	"microservice_gokit_base/src/domain/utils"
+
+	"gotest.tools/assert"
+)
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	assert.Equal(t, w.Header().Get("Content-Type"), ProblemContentType+"; charset=utf-8")
+	var problem Problem
+	assert.NilError(t, json.NewDecoder(w.Body).Decode(&problem))
+	return problem
+}
+
+func TestEncodeProblem(t *testing.T) {
+	ctx := utils.ContextWithRequestID(context.TODO(), "req-1")
+	ctx = contextWithInstance(ctx, httptest.NewRequest("POST", "/api/v1/orders", nil))
+
+	t.Run("WHEN the order is not valid SHOULD answer a validation problem with the field errors", func(t *testing.T) {
+		fields := []domainSvc.FieldError{{Field: "quantity", JSONPath: "$.order_items[0].quantity", Rule: "gt", Message: "must be positive"}}
+		w := httptest.NewRecorder()
+		encodeError(ctx, domainSvc.ValidationError{Fields: fields}, w)
+		assert.Equal(t, w.Code, http.StatusUnprocessableEntity)
+		problem := decodeProblem(t, w)
+		assert.DeepEqual(t, problem, Problem{
+			Type:      "urn:problem-type:validation-error",
+			Title:     "The request is not valid",
+			Status:    http.StatusUnprocessableEntity,
+			Detail:    domainSvc.ValidationError{Fields: fields}.Error(),
+			Instance:  "/api/v1/orders",
+			RequestID: "req-1",
+			Errors:    fields,
+		})
+	})
+	t.Run("WHEN the status changed SHOULD tell the current status", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		encodeError(ctx, domainRepo.StatusConflictError{Expected: "Accepted", Actual: "Cancelled"}, w)
+		assert.Equal(t, w.Code, http.StatusConflict)
+		problem := decodeProblem(t, w)
+		assert.Equal(t, problem.Type, "urn:problem-type:status-conflict")
+		assert.Equal(t, problem.CurrentStatus, "Cancelled")
+	})
+	t.Run("WHEN the status describes the error SHOULD answer a blank problem titled by the status", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		encodeError(ctx, domainRepo.ErrNotFound, w)
+		assert.Equal(t, w.Code, http.StatusNotFound)
+		problem := decodeProblem(t, w)
+		assert.Equal(t, problem.Type, "about:blank")
+		assert.Equal(t, problem.Title, "Not Found")
+		assert.Equal(t, problem.Status, http.StatusNotFound)
+		assert.Equal(t, problem.Detail, domainRepo.ErrNotFound.Error())
+	})
+}
+
+func TestRouterProblems(t *testing.T) {
+	router := newRouter()
+	router.Methods("GET").Path("/orders").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("WHEN no route matches the path SHOULD answer a 404 problem with a request id", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/unknown", nil))
+		assert.Equal(t, w.Code, http.StatusNotFound)
+		problem := decodeProblem(t, w)
+		assert.Equal(t, problem.Instance, "/unknown")
+		assert.Assert(t, problem.RequestID != "")
+		assert.Equal(t, w.Header().Get(RequestIDHeader), problem.RequestID)
+	})
+	t.Run("WHEN the route does not accept the method SHOULD answer a 405 problem", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", "/orders", nil))
+		assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+		assert.Equal(t, decodeProblem(t, w).Title, "Method Not Allowed")
+	})
+	t.Run("WHEN no handler is mounted for the path SHOULD answer a 404 problem", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewNotFound().ServeHTTP(w, httptest.NewRequest("GET", "/nothing", nil))
+		assert.Equal(t, w.Code, http.StatusNotFound)
+		assert.Equal(t, decodeProblem(t, w).Instance, "/nothing")
+	})
+}
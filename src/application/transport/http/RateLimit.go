@@ -0,0 +1,44 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/application/ratelimit"
+)
+
+type rateLimitContextKey struct{}
+
+// rateLimitToContext stores the address of the client and a holder of the rate limit of
+// the request, sent on the RateLimit headers of the response
+func rateLimitToContext(ctx context.Context, r *http.Request) context.Context {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	ctx = endpoints.ContextWithClientIP(ctx, ip)
+	holder := &ratelimit.Result{}
+	ctx = context.WithValue(ctx, rateLimitContextKey{}, holder)
+	return endpoints.ContextWithRateLimitRecorder(ctx, func(result ratelimit.Result) {
+		*holder = result
+	})
+}
+
+// rateLimitHeaders sends the rate limit of the request, when it had one
+func rateLimitHeaders(ctx context.Context, w http.ResponseWriter) context.Context {
+	result, ok := ctx.Value(rateLimitContextKey{}).(*ratelimit.Result)
+	if !ok || result.Limit == 0 {
+		return ctx
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(result.Reset/time.Second)))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(result.RetryAfter/time.Second)))
+	}
+	return ctx
+}
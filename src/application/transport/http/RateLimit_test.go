@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/application/ratelimit"
+	"microservice_gokit_base/src/domain/model"
+	domainSvc "microservice_gokit_base/src/domain/service"
+	"microservice_gokit_base/src/mocks"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	svc := mocks.NewMockIOrderService(mockCtrl)
+	svc.EXPECT().Count(gomock.Any()).Return(int64(1), nil).AnyTimes()
+	limits := map[string]ratelimit.Limit{endpoints.CountName: {Rate: 1, Burst: 1}}
+	router := NewHTTPOrder(endpoints.MakeOrderEndpoints(svc,
+		endpoints.WithRateLimits(ratelimit.NewMemoryStore(), limits, log.NewNopLogger()),
+	), log.NewNopLogger(), testBaseURL)
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", testBaseURL+"orders/count", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("WHEN the client has requests left SHOULD send the rate limit headers", func(t *testing.T) {
+		w := serve("10.0.0.1:1234")
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Header().Get("RateLimit-Limit"), "1")
+		assert.Equal(t, w.Header().Get("RateLimit-Remaining"), "0")
+		assert.Equal(t, w.Header().Get("RateLimit-Reset"), "1")
+	})
+	t.Run("WHEN the client has run out of requests SHOULD respond 429 with Retry-After", func(t *testing.T) {
+		w := serve("10.0.0.1:5678")
+		assert.Equal(t, w.Code, http.StatusTooManyRequests)
+		assert.Equal(t, w.Header().Get("Retry-After"), "1")
+		assert.Equal(t, w.Header().Get("RateLimit-Remaining"), "0")
+	})
+	t.Run("WHEN other client calls SHOULD have its own limit", func(t *testing.T) {
+		assert.Equal(t, serve("10.0.0.2:1234").Code, http.StatusOK)
+	})
+	t.Run("WHEN the credentials are wrong SHOULD limit the client too", func(t *testing.T) {
+		apiKeys := mocks.NewMockIAPIKeyService(mockCtrl)
+		apiKeys.EXPECT().Authenticate(gomock.Any(), "guess").Return(model.APIKey{}, domainSvc.ErrAPIKeyRejected)
+		router := NewHTTPOrder(endpoints.MakeOrderEndpoints(svc,
+			endpoints.WithRateLimits(ratelimit.NewMemoryStore(), limits, log.NewNopLogger()),
+			endpoints.WithAPIKeys(apiKeys, endpoints.CredentialPolicy{Required: true}),
+		), log.NewNopLogger(), testBaseURL)
+		codes := []int{}
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest("GET", testBaseURL+"orders/count", nil)
+			r.Header.Set(APIKeyHeader, "guess")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			codes = append(codes, w.Code)
+		}
+		assert.DeepEqual(t, codes, []int{http.StatusUnauthorized, http.StatusTooManyRequests})
+	})
+	t.Run("WHEN the route has no limit SHOULD not send the headers", func(t *testing.T) {
+		r := httptest.NewRequest("GET", testBaseURL+"orders/id/1", nil)
+		svc.EXPECT().GetByID(gomock.Any(), "1").Return(model.Order{ID: "1", Version: 1}, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		assert.Equal(t, w.Header().Get("RateLimit-Limit"), "")
+	})
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"microservice_gokit_base/src/application/endpoints"
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+)
+
+// NewRecover answers the panics of next, as the ones of the decoders and encoders, with
+// a 500 problem carrying the request id, logging the stack trace and counting them
+func NewRecover(next http.Handler, logger log.Logger, panics metrics.Counter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			panics.Add(1)
+			ctx := contextWithInstance(r.Context(), r)
+			if id := w.Header().Get(RequestIDHeader); id != "" {
+				ctx = utils.ContextWithRequestID(ctx, id)
+			}
+			level.Error(utils.LoggerFromContext(ctx, logger)).Log(
+				"msg", "http panic", "method", r.Method, "path", r.URL.Path,
+				"panic", p, "stack", string(debug.Stack()))
+			if recorder.wroteHeader {
+				// the response has started, the client sees it truncated
+				return
+			}
+			encodeError(ctx, endpoints.ErrPanic, w)
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
+	"gotest.tools/assert"
+)
+
+func TestRecover(t *testing.T) {
+	panics := generic.NewCounter("panics")
+	router := newRouter()
+	router.Path("/panic").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("decoder failed")
+	})
+	router.Path("/streaming").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("encoder failed")
+	})
+	handler := NewRecover(router, log.NewNopLogger(), panics)
+
+	t.Run("WHEN the handler panics SHOULD answer a 500 problem with the request id", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/panic", nil)
+		r.Header.Set(RequestIDHeader, "req-1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, w.Code, http.StatusInternalServerError)
+		var body map[string]interface{}
+		assert.NilError(t, json.NewDecoder(w.Body).Decode(&body))
+		assert.DeepEqual(t, body, map[string]interface{}{
+			"type":       "urn:problem-type:internal-error",
+			"title":      "Internal error",
+			"status":     float64(http.StatusInternalServerError),
+			"detail":     "the request could not be handled because of an internal error",
+			"instance":   "/panic",
+			"request_id": "req-1",
+		})
+		assert.Equal(t, panics.Value(), float64(1))
+	})
+	t.Run("WHEN the response has started SHOULD not write an error after it", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/streaming", nil))
+		assert.Equal(t, w.Code, http.StatusOK)
+		assert.Equal(t, w.Body.String(), "partial")
+		assert.Equal(t, panics.Value(), float64(2))
+	})
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+
+	"microservice_gokit_base/src/domain/utils"
+)
+
+// RequestIDHeader carries the id correlating a request across services
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest request id accepted from the clients
+const maxRequestIDLength = 128
+
+// requestIDMiddleware reads the request id of the request or generates one, stores
+// it in the context and echoes it on the response
+func requestIDMiddleware(uuid utils.IUUIDGenerator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.GenerateID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(utils.ContextWithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// validRequestID tells if a request id given by a client is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"microservice_gokit_base/src/domain/utils"
+
+	"gotest.tools/assert"
+)
+
+type fixedUUID string
+
+func (u fixedUUID) GenerateID() string { return string(u) }
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := requestIDMiddleware(fixedUUID("generated"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = utils.RequestIDFromContext(r.Context())
+		encodeError(r.Context(), errors.New("failure"), w)
+	}))
+
+	t.Run("WHEN the request has an id SHOULD keep it and echo it", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(RequestIDHeader, "abc-123")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, seen, "abc-123")
+		assert.Equal(t, w.Header().Get(RequestIDHeader), "abc-123")
+		assert.Assert(t, strings.Contains(w.Body.String(), `"request_id":"abc-123"`))
+	})
+	t.Run("WHEN the request has no id SHOULD generate one", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, seen, "generated")
+		assert.Equal(t, w.Header().Get(RequestIDHeader), "generated")
+	})
+	t.Run("WHEN the id is not printable or too long SHOULD replace it", func(t *testing.T) {
+		for _, id := range []string{"with space", "line\nbreak", strings.Repeat("a", maxRequestIDLength+1)} {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set(RequestIDHeader, id)
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			assert.Equal(t, seen, "generated")
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"microservice_gokit_base/src/application/endpoints"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// ErrInvalidClientCA when the client CA bundle has no certificates
+var ErrInvalidClientCA = errors.New("the client CA bundle has no PEM certificates")
+
+// CertificateReloader serves the certificate of a pair of files, loading it again
+// when the files change so rotated certificates are used without a restart
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   log.Logger
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modified  time.Time
+	checkedAt time.Time
+}
+
+// NewCertificateReloader loads the certificate, checking the files for changes at most once per interval
+func NewCertificateReloader(certFile string, keyFile string, interval time.Duration,
+	logger log.Logger) (*CertificateReloader, error) {
+	c := &CertificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+	modified, err := c.lastModified()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.load(modified); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate, a failed reload keeps the current certificate
+func (c *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.checkedAt) < c.interval {
+		return c.cert, nil
+	}
+	c.checkedAt = now
+	modified, err := c.lastModified()
+	if err != nil {
+		level.Error(c.logger).Log("msg", "unable to check the certificate", "err", err)
+		return c.cert, nil
+	}
+	if modified.After(c.modified) {
+		if err := c.load(modified); err != nil {
+			level.Error(c.logger).Log("msg", "unable to reload the certificate", "err", err)
+		} else {
+			level.Info(c.logger).Log("msg", "certificate reloaded", "file", c.certFile)
+		}
+	}
+	return c.cert, nil
+}
+
+func (c *CertificateReloader) load(modified time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	c.modified = modified
+	return nil
+}
+
+// lastModified returns the latest modification time of the files
+func (c *CertificateReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// NewTLSConfig returns the TLS configuration of the server, requiring client
+// certificates signed by the CA bundle when clientCAFile is given
+func NewTLSConfig(certificates *CertificateReloader, clientCAFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificates.GetCertificate,
+	}
+	if clientCAFile == "" {
+		return config, nil
+	}
+	bundle, err := ioutil.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, ErrInvalidClientCA
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
+
+// clientIdentityMiddleware stores the identity of the verified client certificate in the context
+func clientIdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cert := r.TLS.VerifiedChains[0][0]
+		identity := endpoints.ClientIdentity{
+			CommonName:   cert.Subject.CommonName,
+			Organization: cert.Subject.Organization,
+			DNSNames:     cert.DNSNames,
+			SerialNumber: cert.SerialNumber.String(),
+		}
+		SetAccessLogSubject(r.Context(), identity.CommonName)
+		next.ServeHTTP(w, r.WithContext(endpoints.ContextWithClientIdentity(r.Context(), identity)))
+	})
+}
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	stdlog "log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"microservice_gokit_base/src/application/endpoints"
+
+	"github.com/go-kit/kit/log"
+	"gotest.tools/assert"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+	kpem []byte
+}
+
+func newTestCert(t *testing.T, cn string, serial int64, parent *testCert, client bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"ms-base"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if client {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.ExtKeyUsage = nil
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+	return &testCert{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		kpem: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func writeTestCert(t *testing.T, dir string, c *testCert, modified time.Time) (string, string) {
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	assert.NilError(t, ioutil.WriteFile(certFile, c.pem, 0600))
+	assert.NilError(t, ioutil.WriteFile(keyFile, c.kpem, 0600))
+	assert.NilError(t, os.Chtimes(certFile, modified, modified))
+	assert.NilError(t, os.Chtimes(keyFile, modified, modified))
+	return certFile, keyFile
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, "ca", 1, nil, false)
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "localhost", 2, ca, false), start)
+
+	reloader, err := NewCertificateReloader(certFile, keyFile, time.Minute, log.NewNopLogger())
+	assert.NilError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	t.Run("WHEN the files are rotated SHOULD serve the new certificate after the interval", func(t *testing.T) {
+		writeTestCert(t, dir, newTestCert(t, "localhost", 3, ca, false), start.Add(time.Minute))
+		cert, err := reloader.GetCertificate(nil)
+		assert.NilError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NilError(t, err)
+		assert.Equal(t, leaf.SerialNumber.Int64(), int64(3))
+
+		writeTestCert(t, dir, newTestCert(t, "localhost", 4, ca, false), start.Add(2*time.Minute))
+		now = now.Add(30 * time.Second)
+		cert, _ = reloader.GetCertificate(nil)
+		leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+		assert.Equal(t, leaf.SerialNumber.Int64(), int64(3))
+
+		now = now.Add(time.Minute)
+		cert, _ = reloader.GetCertificate(nil)
+		leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+		assert.Equal(t, leaf.SerialNumber.Int64(), int64(4))
+	})
+	t.Run("WHEN the new files are invalid SHOULD keep the current certificate", func(t *testing.T) {
+		assert.NilError(t, ioutil.WriteFile(certFile, []byte("invalid"), 0600))
+		assert.NilError(t, os.Chtimes(certFile, start.Add(time.Hour), start.Add(time.Hour)))
+		now = now.Add(time.Hour)
+		cert, err := reloader.GetCertificate(nil)
+		assert.NilError(t, err)
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		assert.Equal(t, leaf.SerialNumber.Int64(), int64(4))
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mtls")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, "ca", 1, nil, false)
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "localhost", 2, ca, false), time.Now())
+	caFile := filepath.Join(dir, "ca.crt")
+	assert.NilError(t, ioutil.WriteFile(caFile, ca.pem, 0600))
+
+	reloader, err := NewCertificateReloader(certFile, keyFile, time.Minute, log.NewNopLogger())
+	assert.NilError(t, err)
+	config, err := NewTLSConfig(reloader, caFile)
+	assert.NilError(t, err)
+
+	var identity endpoints.ClientIdentity
+	router := newRouter()
+	router.Path("/whoami").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ = endpoints.ClientIdentityFromContext(r.Context())
+	})
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	assert.NilError(t, err)
+	server := &http.Server{Handler: router, ErrorLog: stdlog.New(ioutil.Discard, "", 0)}
+	go server.Serve(listener)
+	defer server.Close()
+	url := "https://" + listener.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	newClient := func(certificates ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certificates,
+		}}}
+	}
+
+	t.Run("WHEN the client has a certificate of the CA SHOULD expose its identity", func(t *testing.T) {
+		client := newTestCert(t, "orders-web", 10, ca, true)
+		pair, err := tls.X509KeyPair(client.pem, client.kpem)
+		assert.NilError(t, err)
+		response, err := newClient(pair).Get(url + "/whoami")
+		assert.NilError(t, err)
+		response.Body.Close()
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.DeepEqual(t, identity, endpoints.ClientIdentity{
+			CommonName:   "orders-web",
+			Organization: []string{"ms-base"},
+			DNSNames:     []string{"orders-web"},
+			SerialNumber: "10",
+		})
+	})
+	t.Run("WHEN the client has no certificate SHOULD reject the connection", func(t *testing.T) {
+		_, err := newClient().Get(url + "/whoami")
+		assert.Assert(t, err != nil)
+	})
+	t.Run("WHEN the CA bundle is invalid SHOULD return an error", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.crt")
+		assert.NilError(t, ioutil.WriteFile(invalid, []byte("invalid"), 0600))
+		_, err := NewTLSConfig(reloader, invalid)
+		assert.Equal(t, err, ErrInvalidClientCA)
+	})
+}
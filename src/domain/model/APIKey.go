@@ -0,0 +1,42 @@
+package model
+
+// Scopes granted to the API keys, orders:admin grants the other ones
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+	ScopeOrdersAdmin = "orders:admin"
+)
+
+// Scopes are the scopes an API key can be issued with
+var Scopes = []string{ScopeOrdersRead, ScopeOrdersWrite, ScopeOrdersAdmin}
+
+// APIKey represents the static credentials of a service-to-service caller,
+// only the hash of the key is stored
+type APIKey struct {
+	ID        string   `json:"id" bson:"_id"`
+	Name      string   `json:"name" bson:"name"`
+	Hash      string   `json:"-" bson:"hash"`
+	Scopes    []string `json:"scopes" bson:"scopes"`
+	CreatedAt int64    `json:"created_at" bson:"created_at"`
+	RevokedAt int64    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// Revoked tells if the key can no longer be used
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != 0
+}
+
+// HasScope tells if the key is granted the scope
+func (k APIKey) HasScope(scope string) bool {
+	return HasScope(k.Scopes, scope)
+}
+
+// HasScope tells if the granted scopes include the scope, the admin scope granting every other
+func HasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope || s == ScopeOrdersAdmin {
+			return true
+		}
+	}
+	return false
+}
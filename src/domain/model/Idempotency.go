@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyRecord represents the stored outcome of a request sent with an idempotency key
+type IdempotencyRecord struct {
+	Key         string    `json:"key" bson:"_id"`
+	RequestHash string    `json:"request_hash" bson:"request_hash"`
+	Response    []byte    `json:"response,omitempty" bson:"response,omitempty"`
+	Completed   bool      `json:"completed" bson:"completed"`
+	ExpiresAt   time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// Expired tells if the record is no longer valid at the given time
+func (r IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
@@ -1,19 +1,95 @@
 package model
 
-// Order represents an client order
+// Order statuses handled by the service
+const (
+	StatusPending   = "Pending"
+	StatusAccepted  = "Accepted"
+	StatusPreparing = "Preparing"
+	StatusDelivered = "Delivered"
+	StatusCancelled = "Cancelled"
+	StatusRejected  = "Rejected"
+)
+
+// statusTimestamp is the lifecycle timestamp of a status, by its stored field and on the order
+type statusTimestamp struct {
+	field string
+	of    func(o *Order) *int64
+}
+
+// statusTimestamps maps a status to its lifecycle timestamp, used both by ApplyStatusChange
+// and by the repositories updating the stored field
+var statusTimestamps = map[string]statusTimestamp{
+	StatusAccepted:  {"accepted_on", func(o *Order) *int64 { return &o.AcceptedOn }},
+	StatusPreparing: {"preparing_on", func(o *Order) *int64 { return &o.PreparingOn }},
+	StatusDelivered: {"delivered_on", func(o *Order) *int64 { return &o.DeliveredOn }},
+	StatusCancelled: {"cancelled_on", func(o *Order) *int64 { return &o.CancelledOn }},
+	StatusRejected:  {"rejected_on", func(o *Order) *int64 { return &o.RejectedOn }},
+}
+
+// statusTransitions lists the statuses an order can move to from each status
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusAccepted, StatusRejected, StatusCancelled},
+	StatusAccepted:  {StatusPreparing, StatusCancelled},
+	StatusPreparing: {StatusDelivered, StatusCancelled},
+}
+
+// Order represents an client order, the fields tagged readonly are filled by the service
 type Order struct {
-	ID           string      `json:"id,omitempty" bson:"_id"`
-	CustomerID   string      `json:"customer_id" bson:"customer_id"`
-	Status       string      `json:"status" bson:"status"`
-	CreatedOn    int64       `json:"created_on,omitempty" bson:"created_on,omitempty"`
-	RestaurantID string      `json:"restaurant_id" bson:"restaurant_id" validate:"nonzero"`
-	OrderItems   []OrderItem `json:"order_items,omitempty" bson:"order_items,omitempty" validate:"nonzero, min=1"`
+	ID            string         `json:"id,omitempty" bson:"_id" openapi:"readonly"`
+	CustomerID    string         `json:"customer_id" bson:"customer_id"`
+	Status        string         `json:"status" bson:"status" openapi:"readonly"`
+	CreatedOn     int64          `json:"created_on,omitempty" bson:"created_on,omitempty" openapi:"readonly"`
+	UpdatedOn     int64          `json:"updated_on,omitempty" bson:"updated_on,omitempty" openapi:"readonly"`
+	AcceptedOn    int64          `json:"accepted_on,omitempty" bson:"accepted_on,omitempty" openapi:"readonly"`
+	PreparingOn   int64          `json:"preparing_on,omitempty" bson:"preparing_on,omitempty" openapi:"readonly"`
+	DeliveredOn   int64          `json:"delivered_on,omitempty" bson:"delivered_on,omitempty" openapi:"readonly"`
+	CancelledOn   int64          `json:"cancelled_on,omitempty" bson:"cancelled_on,omitempty" openapi:"readonly"`
+	RejectedOn    int64          `json:"rejected_on,omitempty" bson:"rejected_on,omitempty" openapi:"readonly"`
+	RestaurantID  string         `json:"restaurant_id" bson:"restaurant_id" validate:"nonzero"`
+	OrderItems    []OrderItem    `json:"order_items,omitempty" bson:"order_items,omitempty" validate:"nonzero, min=1, max=100"`
+	StatusHistory []StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty" openapi:"readonly"`
+	Version       int64          `json:"version" bson:"version" openapi:"readonly"`
 }
 
 // OrderItem represents items in an order
 type OrderItem struct {
-	ProductCode string  `json:"product_code" bson:"product_code"`
+	ProductCode string  `json:"product_code" bson:"product_code" validate:"nonzero"`
 	Name        string  `json:"name" bson:"name"`
-	UnitPrice   float32 `json:"unit_price" bson:"unit_price"`
-	Quantity    int32   `json:"quantity" bson:"quantity"`
+	UnitPrice   float32 `json:"unit_price" bson:"unit_price" validate:"min=0"`
+	Quantity    int32   `json:"quantity" bson:"quantity" validate:"min=1"`
+}
+
+// StatusChange represents a transition of the order status
+type StatusChange struct {
+	Status    string `json:"status" bson:"status"`
+	Timestamp int64  `json:"timestamp" bson:"timestamp"`
+	Actor     string `json:"actor,omitempty" bson:"actor,omitempty"`
+	Note      string `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// StatusTimestampField returns the stored field name of the lifecycle timestamp for a status
+func StatusTimestampField(status string) (string, bool) {
+	timestamp, ok := statusTimestamps[status]
+	return timestamp.field, ok
+}
+
+// CanTransition tells if an order can move from a status to another
+func CanTransition(from string, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyStatusChange moves the order to a new status, recording it on the history
+// and on the lifecycle timestamp of the status
+func (o *Order) ApplyStatusChange(change StatusChange) {
+	o.Status = change.Status
+	o.UpdatedOn = change.Timestamp
+	if timestamp, ok := statusTimestamps[change.Status]; ok {
+		*timestamp.of(o) = change.Timestamp
+	}
+	o.StatusHistory = append(o.StatusHistory, change)
 }
@@ -0,0 +1,35 @@
+package model
+
+// Roles of the callers of the service
+const (
+	// RoleCustomer sees and cancels the orders of its CustomerID
+	RoleCustomer = "customer"
+	// RoleStaff handles the orders of its RestaurantID
+	RoleStaff = "staff"
+	// RoleAdmin handles every order
+	RoleAdmin = "admin"
+	// RoleService is a trusted caller, as an API key or a mutual TLS client,
+	// whose access is limited by its scopes instead of the ownership of the orders
+	RoleService = "service"
+)
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	Subject      string
+	Role         string
+	CustomerID   string
+	RestaurantID string
+}
+
+// Owns tells if the order belongs to the caller, always true for admins and services
+func (p Principal) Owns(order Order) bool {
+	switch p.Role {
+	case RoleAdmin, RoleService:
+		return true
+	case RoleStaff:
+		return p.RestaurantID != "" && order.RestaurantID == p.RestaurantID
+	case RoleCustomer:
+		return p.CustomerID != "" && order.CustomerID == p.CustomerID
+	}
+	return false
+}
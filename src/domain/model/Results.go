@@ -0,0 +1,22 @@
+package model
+
+// CreateResult holds the outcome of an order created in a batch
+type CreateResult struct {
+	ID  string
+	Err error
+}
+
+// Outcomes of an order in a bulk status change
+const (
+	OutcomeUpdated           = "updated"
+	OutcomeNotFound          = "not_found"
+	OutcomeInvalidTransition = "invalid_transition"
+	OutcomeConflict          = "conflict"
+)
+
+// StatusChangeResult holds the outcome of an order in a bulk status change
+type StatusChangeResult struct {
+	ID             string
+	PreviousStatus string
+	Outcome        string
+}
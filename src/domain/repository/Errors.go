@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound when the requested order does not exist
+	ErrNotFound = errors.New("error no results found")
+	// ErrDuplicated when an order with the same id already exists
+	ErrDuplicated = errors.New("an order with the same id already exists")
+	// ErrVersionConflict when the order was modified since the expected version
+	ErrVersionConflict = errors.New("the order was modified by another request")
+)
+
+// StatusConflictError when the order is not in the expected status
+type StatusConflictError struct {
+	Expected string
+	Actual   string
+}
+
+func (e StatusConflictError) Error() string {
+	return fmt.Sprintf("the order status is %s, expected %s", e.Actual, e.Expected)
+}
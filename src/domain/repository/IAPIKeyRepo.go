@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+// IAPIKeyRepository decribes the repository of API keys
+type IAPIKeyRepository interface {
+	Create(ctx context.Context, key model.APIKey) error
+	// GetByHash returns the key with the hash, ErrNotFound when there is none
+	GetByHash(ctx context.Context, hash string) (model.APIKey, error)
+	// Revoke marks the key as revoked at the timestamp, ErrNotFound when there is none
+	Revoke(ctx context.Context, id string, revokedAt int64) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+// IIdempotencyRepository decribes the repository of idempotency keys
+type IIdempotencyRepository interface {
+	// Reserve stores the record when its key is free or expired, otherwise it
+	// returns the record already stored and false
+	Reserve(ctx context.Context, record model.IdempotencyRecord) (model.IdempotencyRecord, bool, error)
+	// Complete saves the response of a reserved key
+	Complete(ctx context.Context, key string, response []byte) error
+	// Release frees a reserved key that has not been completed
+	Release(ctx context.Context, key string) error
+}
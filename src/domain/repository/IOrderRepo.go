@@ -9,9 +9,44 @@ import (
 // IOrderRepository decribes the repository of Order
 type IOrderRepository interface {
 	CreateOrder(ctx context.Context, order model.Order) (string, error)
+	// CreateOrders inserts every order it can, returning the error of each
+	// order at its index, nil when the order was inserted
+	CreateOrders(ctx context.Context, orders []model.Order) ([]error, error)
 	GetOrderByID(ctx context.Context, id string) (model.Order, error)
-	ChangeOrderStatus(ctx context.Context, id string, status string) (int64, error)
-	GetAll(ctx context.Context) ([]*model.Order, error)
-	GetPage(ctx context.Context, page int64, size int64) ([]*model.Order, error)
+	ChangeOrderStatus(ctx context.Context, id string, change model.StatusChange, cond UpdateCondition) (int64, error)
+	// ChangeOrdersStatus changes at once the status of the given orders meeting the condition
+	ChangeOrdersStatus(ctx context.Context, ids []string, change model.StatusChange, cond UpdateCondition) (int64, error)
+	GetAll(ctx context.Context, filter OrderFilter) ([]*model.Order, error)
+	GetPage(ctx context.Context, filter OrderFilter, page int64, size int64) ([]*model.Order, error)
+	// Iterate returns an iterator over the orders of the filter, to read them one by one
+	Iterate(ctx context.Context, filter OrderFilter) (IOrderIterator, error)
 	Count(ctx context.Context) (int64, error)
+	// CountFilter counts the orders of the filter without reading them
+	CountFilter(ctx context.Context, filter OrderFilter) (int64, error)
+}
+
+// IOrderIterator decribes a cursor over the orders of a query
+type IOrderIterator interface {
+	Next(ctx context.Context) bool
+	Decode(order *model.Order) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// UpdateCondition holds the preconditions of a write, zero values are not checked
+type UpdateCondition struct {
+	// Version expected for the order before the write, zero for the orders stored before the versions
+	Version int64
+	// CheckVersion makes the write conditional on Version
+	CheckVersion bool
+	// Status expected for the order before the write
+	Status string
+}
+
+// OrderFilter holds the criteria to select orders, zero values match any order
+type OrderFilter struct {
+	IDs          []string
+	RestaurantID string
+	CustomerID   string
+	Status       string
 }
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// APIKeyPrefix starts every issued key, telling them apart from the bearer tokens
+const APIKeyPrefix = "upk_"
+
+// apiKeySize is the number of random bytes of a key
+const apiKeySize = 32
+
+var (
+	// ErrAPIKeyWithoutName when a key is issued without a name
+	ErrAPIKeyWithoutName = errors.New("the API key needs a name")
+	// ErrAPIKeyWithoutScopes when a key is issued without scopes
+	ErrAPIKeyWithoutScopes = errors.New("the API key needs at least one scope")
+	// ErrAPIKeyRejected when the key is unknown or has been revoked
+	ErrAPIKeyRejected = errors.New("the API key is unknown or revoked")
+)
+
+// UnknownScopeError when a key is issued with a scope that does not exist
+type UnknownScopeError struct {
+	Scope string
+}
+
+func (e UnknownScopeError) Error() string {
+	return fmt.Sprintf("the scope %q is unknown, expected one of %s", e.Scope, strings.Join(model.Scopes, ", "))
+}
+
+// IAPIKeyService describes the API key service.
+type IAPIKeyService interface {
+	// Issue creates a key returning it in plain text, the only time it is available
+	Issue(ctx context.Context, name string, scopes []string) (model.APIKey, string, error)
+	Revoke(ctx context.Context, id string) error
+	// Authenticate returns the key matching the plain text one, ErrAPIKeyRejected when none does
+	Authenticate(ctx context.Context, key string) (model.APIKey, error)
+}
+
+// APIKeyService instance
+type APIKeyService struct {
+	repository repository.IAPIKeyRepository
+	uuid       utils.IUUIDGenerator
+	date       utils.IDateGenerator
+	logger     log.Logger
+	random     io.Reader
+}
+
+// NewAPIKeyService creates and returns a new API key service instance
+func NewAPIKeyService(rep repository.IAPIKeyRepository, uuid utils.IUUIDGenerator,
+	date utils.IDateGenerator, logger log.Logger) IAPIKeyService {
+	return &APIKeyService{
+		repository: rep,
+		uuid:       uuid,
+		date:       date,
+		logger:     logger,
+		random:     rand.Reader,
+	}
+}
+
+// Issue creates a key with the scopes
+func (s *APIKeyService) Issue(ctx context.Context, name string, scopes []string) (model.APIKey, string, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "Issue")
+	if strings.TrimSpace(name) == "" {
+		return model.APIKey{}, "", ErrAPIKeyWithoutName
+	}
+	if len(scopes) == 0 {
+		return model.APIKey{}, "", ErrAPIKeyWithoutScopes
+	}
+	if err := CheckScopes(scopes); err != nil {
+		return model.APIKey{}, "", err
+	}
+
+	secret := make([]byte, apiKeySize)
+	if _, err := io.ReadFull(s.random, secret); err != nil {
+		level.Error(logger).Log("err", err)
+		return model.APIKey{}, "", err
+	}
+	plain := APIKeyPrefix + hex.EncodeToString(secret)
+	key := model.APIKey{
+		ID:        s.uuid.GenerateID(),
+		Name:      name,
+		Hash:      hashAPIKey(plain),
+		Scopes:    scopes,
+		CreatedAt: s.date.NowTimestamp(),
+	}
+	if err := s.repository.Create(ctx, key); err != nil {
+		level.Error(logger).Log("err", err)
+		return model.APIKey{}, "", err
+	}
+	level.Info(logger).Log("msg", "API key issued", "key_id", key.ID, "scopes", strings.Join(scopes, ","))
+	return key, plain, nil
+}
+
+// Revoke stops accepting a key
+func (s *APIKeyService) Revoke(ctx context.Context, id string) error {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "Revoke")
+	if err := s.repository.Revoke(ctx, id, s.date.NowTimestamp()); err != nil {
+		if err != repository.ErrNotFound {
+			level.Error(logger).Log("err", err)
+		}
+		return err
+	}
+	level.Info(logger).Log("msg", "API key revoked", "key_id", id)
+	return nil
+}
+
+// Authenticate returns the key matching the plain text one
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (model.APIKey, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return model.APIKey{}, ErrAPIKeyRejected
+	}
+	stored, err := s.repository.GetByHash(ctx, hashAPIKey(key))
+	if err == repository.ErrNotFound {
+		return model.APIKey{}, ErrAPIKeyRejected
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	if stored.Revoked() {
+		return model.APIKey{}, ErrAPIKeyRejected
+	}
+	return stored, nil
+}
+
+// hashAPIKey returns the SHA-256 of the key, enough for random keys of 256 bits
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckScopes returns an UnknownScopeError for the first scope that does not exist
+func CheckScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !knownScope(scope) {
+			return UnknownScopeError{Scope: scope}
+		}
+	}
+	return nil
+}
+
+func knownScope(scope string) bool {
+	for _, s := range model.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
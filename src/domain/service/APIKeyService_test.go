@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/mocks"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		repo    = mocks.NewMockIAPIKeyRepository(mockCtrl)
+		uuidGen = mocks.NewMockIUUIDGenerator(mockCtrl)
+		dateGen = mocks.NewMockIDateGenerator(mockCtrl)
+		ctx     = context.TODO()
+		secret  = bytes.Repeat([]byte{0xab}, apiKeySize)
+		plain   = APIKeyPrefix + strings.Repeat("ab", apiKeySize)
+	)
+	newService := func() *APIKeyService {
+		return &APIKeyService{
+			repository: repo,
+			uuid:       uuidGen,
+			date:       dateGen,
+			logger:     log.NewNopLogger(),
+			random:     bytes.NewReader(secret),
+		}
+	}
+
+	t.Run("APIKeyService.Issue", func(t *testing.T) {
+		t.Run("WHEN the scopes are known SHOULD store the hash and return the key once", func(t *testing.T) {
+			uuidGen.EXPECT().GenerateID().Return("k1")
+			dateGen.EXPECT().NowTimestamp().Return(int64(100))
+			expected := model.APIKey{
+				ID:        "k1",
+				Name:      "pos",
+				Hash:      hashAPIKey(plain),
+				Scopes:    []string{model.ScopeOrdersRead},
+				CreatedAt: 100,
+			}
+			repo.EXPECT().Create(ctx, expected).Return(nil)
+
+			key, issued, err := newService().Issue(ctx, "pos", []string{model.ScopeOrdersRead})
+			assert.NilError(t, err)
+			assert.Equal(t, issued, plain)
+			assert.DeepEqual(t, key, expected)
+			assert.Assert(t, key.Hash != issued)
+		})
+		t.Run("WHEN the name or the scopes are missing SHOULD fail", func(t *testing.T) {
+			_, _, err := newService().Issue(ctx, " ", []string{model.ScopeOrdersRead})
+			assert.Equal(t, err, ErrAPIKeyWithoutName)
+			_, _, err = newService().Issue(ctx, "pos", nil)
+			assert.Equal(t, err, ErrAPIKeyWithoutScopes)
+		})
+		t.Run("WHEN a scope is unknown SHOULD fail with an unknown scope error", func(t *testing.T) {
+			_, _, err := newService().Issue(ctx, "pos", []string{model.ScopeOrdersRead, "orders:delete"})
+			assert.DeepEqual(t, err, UnknownScopeError{Scope: "orders:delete"})
+		})
+	})
+
+	t.Run("APIKeyService.Authenticate", func(t *testing.T) {
+		t.Run("WHEN the key is active SHOULD return it", func(t *testing.T) {
+			stored := model.APIKey{ID: "k1", Scopes: []string{model.ScopeOrdersWrite}}
+			repo.EXPECT().GetByHash(ctx, hashAPIKey(plain)).Return(stored, nil)
+			key, err := newService().Authenticate(ctx, plain)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, key, stored)
+		})
+		t.Run("WHEN the key is unknown or revoked SHOULD reject it", func(t *testing.T) {
+			repo.EXPECT().GetByHash(ctx, hashAPIKey(plain)).Return(model.APIKey{}, domainRepo.ErrNotFound)
+			_, err := newService().Authenticate(ctx, plain)
+			assert.Equal(t, err, ErrAPIKeyRejected)
+
+			repo.EXPECT().GetByHash(ctx, hashAPIKey(plain)).Return(model.APIKey{ID: "k1", RevokedAt: 5}, nil)
+			_, err = newService().Authenticate(ctx, plain)
+			assert.Equal(t, err, ErrAPIKeyRejected)
+		})
+		t.Run("WHEN the key has not the prefix SHOULD reject it without a lookup", func(t *testing.T) {
+			_, err := newService().Authenticate(ctx, "a.jwt.token")
+			assert.Equal(t, err, ErrAPIKeyRejected)
+		})
+	})
+
+	t.Run("APIKeyService.Revoke", func(t *testing.T) {
+		t.Run("WHEN the key exists SHOULD revoke it now", func(t *testing.T) {
+			dateGen.EXPECT().NowTimestamp().Return(int64(200))
+			repo.EXPECT().Revoke(ctx, "k1", int64(200)).Return(nil)
+			assert.NilError(t, newService().Revoke(ctx, "k1"))
+		})
+		t.Run("WHEN the key does not exist SHOULD fail with not found", func(t *testing.T) {
+			dateGen.EXPECT().NowTimestamp().Return(int64(200))
+			repo.EXPECT().Revoke(ctx, "k2", int64(200)).Return(domainRepo.ErrNotFound)
+			assert.Equal(t, newService().Revoke(ctx, "k2"), domainRepo.ErrNotFound)
+		})
+	})
+}
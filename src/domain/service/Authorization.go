@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"microservice_gokit_base/src/domain/model"
+	"microservice_gokit_base/src/domain/repository"
+)
+
+var (
+	// ErrPrincipalMissing when the caller of the request is not identified
+	ErrPrincipalMissing = errors.New("auth: the caller is not identified")
+	// ErrNotAllowed when the caller cannot act on the orders
+	ErrNotAllowed = errors.New("the caller is not allowed to act on the orders")
+)
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a context carrying the caller of the request
+func ContextWithPrincipal(ctx context.Context, principal model.Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller of the request
+func PrincipalFromContext(ctx context.Context) (model.Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(model.Principal)
+	return principal, ok
+}
+
+// authorizationService checks the caller of every method before calling the next service
+type authorizationService struct {
+	next IOrderService
+}
+
+// NewAuthorizationService decorates the service so customers only see and cancel their
+// own orders, staff only handle the orders of their restaurant and admins every order.
+// The listings are scoped to the orders of the caller and the orders of others are
+// reported as not found.
+func NewAuthorizationService(next IOrderService) IOrderService {
+	return &authorizationService{next: next}
+}
+
+// principal returns the caller, failing when it is missing or has an unknown role
+func principal(ctx context.Context) (model.Principal, error) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return model.Principal{}, ErrPrincipalMissing
+	}
+	switch p.Role {
+	case model.RoleAdmin, model.RoleService, model.RoleStaff, model.RoleCustomer:
+		return p, nil
+	}
+	return model.Principal{}, ErrNotAllowed
+}
+
+// scope restricts the filter to the orders of the caller, failing when it asks for others
+func scope(p model.Principal, filter repository.OrderFilter) (repository.OrderFilter, error) {
+	switch p.Role {
+	case model.RoleStaff:
+		if p.RestaurantID == "" || (filter.RestaurantID != "" && filter.RestaurantID != p.RestaurantID) {
+			return filter, ErrNotAllowed
+		}
+		filter.RestaurantID = p.RestaurantID
+	case model.RoleCustomer:
+		if p.CustomerID == "" || (filter.CustomerID != "" && filter.CustomerID != p.CustomerID) {
+			return filter, ErrNotAllowed
+		}
+		filter.CustomerID = p.CustomerID
+	}
+	return filter, nil
+}
+
+// creatable tells if the caller can create the order, the customers on their own name
+func creatable(p model.Principal, order model.Order) bool {
+	if p.Role == model.RoleCustomer && order.CustomerID == "" {
+		order.CustomerID = p.CustomerID
+	}
+	return p.Owns(order)
+}
+
+// owned returns the order when it belongs to the caller, not found otherwise
+func (s *authorizationService) owned(ctx context.Context, p model.Principal, id string) (model.Order, error) {
+	order, err := s.next.GetByID(ctx, id)
+	if err != nil {
+		return order, err
+	}
+	if !p.Owns(order) {
+		return model.Order{}, repository.ErrNotFound
+	}
+	return order, nil
+}
+
+func (s *authorizationService) Create(ctx context.Context, order model.Order) (string, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !creatable(p, order) {
+		return "", ErrNotAllowed
+	}
+	if p.Role == model.RoleCustomer {
+		order.CustomerID = p.CustomerID
+	}
+	return s.next.Create(ctx, order)
+}
+
+func (s *authorizationService) CreateBatch(ctx context.Context, orders []model.Order) ([]model.CreateResult, error) {
+	p, err := s.creatableAll(ctx, orders)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.CreateBatch(ctx, ownedBy(p, orders))
+}
+
+func (s *authorizationService) Import(ctx context.Context, orders []model.Order) ([]model.CreateResult, error) {
+	p, err := s.creatableAll(ctx, orders)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.Import(ctx, ownedBy(p, orders))
+}
+
+// creatableAll fails unless the caller can create every order
+func (s *authorizationService) creatableAll(ctx context.Context, orders []model.Order) (model.Principal, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return p, err
+	}
+	for _, order := range orders {
+		if !creatable(p, order) {
+			return p, ErrNotAllowed
+		}
+	}
+	return p, nil
+}
+
+// ownedBy sets the customer of the orders created by a customer
+func ownedBy(p model.Principal, orders []model.Order) []model.Order {
+	if p.Role != model.RoleCustomer {
+		return orders
+	}
+	owned := make([]model.Order, len(orders))
+	for i, order := range orders {
+		order.CustomerID = p.CustomerID
+		owned[i] = order
+	}
+	return owned
+}
+
+func (s *authorizationService) GetByID(ctx context.Context, id string) (model.Order, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return model.Order{}, err
+	}
+	return s.owned(ctx, p, id)
+}
+
+func (s *authorizationService) GetAll(ctx context.Context, filter repository.OrderFilter) ([]*model.Order, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filter, err = scope(p, filter); err != nil {
+		return nil, err
+	}
+	return s.next.GetAll(ctx, filter)
+}
+
+func (s *authorizationService) GetPage(ctx context.Context, filter repository.OrderFilter,
+	page int64, size int64) ([]*model.Order, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filter, err = scope(p, filter); err != nil {
+		return nil, err
+	}
+	return s.next.GetPage(ctx, filter, page, size)
+}
+
+func (s *authorizationService) Export(ctx context.Context, filter repository.OrderFilter) (repository.IOrderIterator, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filter, err = scope(p, filter); err != nil {
+		return nil, err
+	}
+	return s.next.Export(ctx, filter)
+}
+
+// ChangeStatus lets the customers only cancel their orders
+func (s *authorizationService) ChangeStatus(ctx context.Context, id string, change model.StatusChange,
+	cond repository.UpdateCondition) (int64, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if p.Role == model.RoleCustomer && change.Status != model.StatusCancelled {
+		return 0, ErrNotAllowed
+	}
+	if _, err := s.owned(ctx, p, id); err != nil {
+		return 0, err
+	}
+	return s.next.ChangeStatus(ctx, id, change, cond)
+}
+
+// ChangeStatusBulk only changes the orders of the caller, the customers cannot use it
+func (s *authorizationService) ChangeStatusBulk(ctx context.Context, filter repository.OrderFilter,
+	change model.StatusChange) ([]model.StatusChangeResult, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.Role == model.RoleCustomer {
+		return nil, ErrNotAllowed
+	}
+	if filter, err = scope(p, filter); err != nil {
+		return nil, err
+	}
+	return s.next.ChangeStatusBulk(ctx, filter, change)
+}
+
+func (s *authorizationService) GetHistory(ctx context.Context, id string) ([]model.StatusChange, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.owned(ctx, p, id); err != nil {
+		return nil, err
+	}
+	return s.next.GetHistory(ctx, id)
+}
+
+// Count counts the orders of the caller
+func (s *authorizationService) Count(ctx context.Context) (int64, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return -1, err
+	}
+	if p.Role == model.RoleAdmin || p.Role == model.RoleService {
+		return s.next.Count(ctx)
+	}
+	filter, err := scope(p, repository.OrderFilter{})
+	if err != nil {
+		return -1, err
+	}
+	return s.next.CountFilter(ctx, filter)
+}
+
+// CountFilter counts the orders of the filter belonging to the caller
+func (s *authorizationService) CountFilter(ctx context.Context, filter repository.OrderFilter) (int64, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return -1, err
+	}
+	if filter, err = scope(p, filter); err != nil {
+		return -1, err
+	}
+	return s.next.CountFilter(ctx, filter)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/mocks"
+)
+
+func TestAuthorizationService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		next     = mocks.NewMockIOrderService(mockCtrl)
+		svc      = NewAuthorizationService(next)
+		customer = ContextWithPrincipal(context.TODO(), model.Principal{Role: model.RoleCustomer, CustomerID: "c1"})
+		staff    = ContextWithPrincipal(context.TODO(), model.Principal{Role: model.RoleStaff, RestaurantID: "r1"})
+		admin    = ContextWithPrincipal(context.TODO(), model.Principal{Role: model.RoleAdmin})
+		order    = model.Order{ID: "1", CustomerID: "c1", RestaurantID: "r1", Status: model.StatusPending}
+		other    = model.Order{ID: "2", CustomerID: "c2", RestaurantID: "r2", Status: model.StatusPending}
+	)
+
+	t.Run("WHEN the caller is not identified SHOULD fail to authenticate", func(t *testing.T) {
+		_, err := svc.GetByID(context.TODO(), "1")
+		assert.Equal(t, err, ErrPrincipalMissing)
+	})
+
+	t.Run("authorizationService.GetByID", func(t *testing.T) {
+		t.Run("WHEN the order belongs to the caller SHOULD return it", func(t *testing.T) {
+			next.EXPECT().GetByID(customer, "1").Return(order, nil)
+			found, err := svc.GetByID(customer, "1")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, found, order)
+
+			next.EXPECT().GetByID(staff, "1").Return(order, nil)
+			_, err = svc.GetByID(staff, "1")
+			assert.NilError(t, err)
+		})
+		t.Run("WHEN the order belongs to others SHOULD report it as not found", func(t *testing.T) {
+			next.EXPECT().GetByID(customer, "2").Return(other, nil)
+			_, err := svc.GetByID(customer, "2")
+			assert.Equal(t, err, domainRepo.ErrNotFound)
+
+			next.EXPECT().GetByID(staff, "2").Return(other, nil)
+			_, err = svc.GetByID(staff, "2")
+			assert.Equal(t, err, domainRepo.ErrNotFound)
+		})
+		t.Run("WHEN the caller is an admin SHOULD return any order", func(t *testing.T) {
+			next.EXPECT().GetByID(admin, "2").Return(other, nil)
+			_, err := svc.GetByID(admin, "2")
+			assert.NilError(t, err)
+		})
+	})
+
+	t.Run("authorizationService.GetAll", func(t *testing.T) {
+		t.Run("WHEN the caller lists the orders SHOULD scope them to its identity", func(t *testing.T) {
+			next.EXPECT().GetAll(customer, domainRepo.OrderFilter{CustomerID: "c1", Status: model.StatusPending}).Return(nil, nil)
+			_, err := svc.GetAll(customer, domainRepo.OrderFilter{Status: model.StatusPending})
+			assert.NilError(t, err)
+
+			next.EXPECT().GetPage(staff, domainRepo.OrderFilter{RestaurantID: "r1"}, int64(0), int64(10)).Return(nil, nil)
+			_, err = svc.GetPage(staff, domainRepo.OrderFilter{}, 0, 10)
+			assert.NilError(t, err)
+
+			next.EXPECT().GetAll(admin, domainRepo.OrderFilter{}).Return(nil, nil)
+			_, err = svc.GetAll(admin, domainRepo.OrderFilter{})
+			assert.NilError(t, err)
+		})
+		t.Run("WHEN the caller asks for the orders of others SHOULD not be allowed", func(t *testing.T) {
+			_, err := svc.GetAll(customer, domainRepo.OrderFilter{CustomerID: "c2"})
+			assert.Equal(t, err, ErrNotAllowed)
+			_, err = svc.Export(staff, domainRepo.OrderFilter{RestaurantID: "r2"})
+			assert.Equal(t, err, ErrNotAllowed)
+		})
+	})
+
+	t.Run("authorizationService.Create", func(t *testing.T) {
+		t.Run("WHEN a customer creates an order SHOULD create it on its name", func(t *testing.T) {
+			mine := model.Order{RestaurantID: "r9"}
+			expected := mine
+			expected.CustomerID = "c1"
+			next.EXPECT().Create(customer, expected).Return("3", nil)
+			_, err := svc.Create(customer, mine)
+			assert.NilError(t, err)
+		})
+		t.Run("WHEN the order is for others SHOULD not be allowed", func(t *testing.T) {
+			_, err := svc.Create(customer, other)
+			assert.Equal(t, err, ErrNotAllowed)
+			_, err = svc.CreateBatch(staff, []model.Order{order, other})
+			assert.Equal(t, err, ErrNotAllowed)
+		})
+	})
+
+	t.Run("authorizationService.ChangeStatus", func(t *testing.T) {
+		t.Run("WHEN a customer cancels its order SHOULD change it", func(t *testing.T) {
+			change := model.StatusChange{Status: model.StatusCancelled}
+			next.EXPECT().GetByID(customer, "1").Return(order, nil)
+			next.EXPECT().ChangeStatus(customer, "1", change, domainRepo.UpdateCondition{}).Return(int64(1), nil)
+			_, err := svc.ChangeStatus(customer, "1", change, domainRepo.UpdateCondition{})
+			assert.NilError(t, err)
+		})
+		t.Run("WHEN a customer moves its order to other status SHOULD not be allowed", func(t *testing.T) {
+			_, err := svc.ChangeStatus(customer, "1", model.StatusChange{Status: model.StatusAccepted}, domainRepo.UpdateCondition{})
+			assert.Equal(t, err, ErrNotAllowed)
+		})
+		t.Run("WHEN the staff changes an order of other restaurant SHOULD report it as not found", func(t *testing.T) {
+			next.EXPECT().GetByID(staff, "2").Return(other, nil)
+			_, err := svc.ChangeStatus(staff, "2", model.StatusChange{Status: model.StatusAccepted}, domainRepo.UpdateCondition{})
+			assert.Equal(t, err, domainRepo.ErrNotFound)
+		})
+		t.Run("WHEN the staff changes many orders SHOULD only change the ones of its restaurant", func(t *testing.T) {
+			change := model.StatusChange{Status: model.StatusAccepted}
+			next.EXPECT().ChangeStatusBulk(staff, domainRepo.OrderFilter{IDs: []string{"1", "2"}, RestaurantID: "r1"}, change).Return(nil, nil)
+			_, err := svc.ChangeStatusBulk(staff, domainRepo.OrderFilter{IDs: []string{"1", "2"}}, change)
+			assert.NilError(t, err)
+			_, err = svc.ChangeStatusBulk(customer, domainRepo.OrderFilter{IDs: []string{"1"}}, change)
+			assert.Equal(t, err, ErrNotAllowed)
+		})
+	})
+
+	t.Run("authorizationService.Count", func(t *testing.T) {
+		t.Run("WHEN the caller is not an admin SHOULD count its orders", func(t *testing.T) {
+			next.EXPECT().CountFilter(staff, domainRepo.OrderFilter{RestaurantID: "r1"}).Return(int64(1), nil)
+			counted, err := svc.Count(staff)
+			assert.NilError(t, err)
+			assert.Equal(t, counted, int64(1))
+		})
+		t.Run("WHEN the filter is of others SHOULD not count them", func(t *testing.T) {
+			_, err := svc.CountFilter(customer, domainRepo.OrderFilter{CustomerID: "c2"})
+			assert.Equal(t, err, ErrNotAllowed)
+		})
+	})
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sort"
 
 	"microservice_gokit_base/src/domain/model"
 	"microservice_gokit_base/src/domain/repository"
@@ -9,50 +11,82 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
-	"gopkg.in/validator.v2"
+)
+
+// DefaultMaxBatchSize is the maximum number of orders of a batch when none is configured
+const DefaultMaxBatchSize = 100
+
+var (
+	// ErrEmptyBatch when a batch has no orders
+	ErrEmptyBatch = errors.New("the batch has no orders")
+	// ErrBatchTooLarge when a batch has more orders than allowed
+	ErrBatchTooLarge = errors.New("the batch has too many orders")
+	// ErrBulkWithoutTarget when a bulk change has neither ids nor restaurant
+	ErrBulkWithoutTarget = errors.New("the bulk change needs the ids or the restaurant of the orders")
 )
 
 // IOrderService describes the Order service.
 type IOrderService interface {
 	Create(ctx context.Context, order model.Order) (string, error)
+	CreateBatch(ctx context.Context, orders []model.Order) ([]model.CreateResult, error)
+	Import(ctx context.Context, orders []model.Order) ([]model.CreateResult, error)
 	GetByID(ctx context.Context, id string) (model.Order, error)
-	GetAll(ctx context.Context) ([]*model.Order, error)
-	GetPage(ctx context.Context, page int64, size int64) ([]*model.Order, error)
-	ChangeStatus(ctx context.Context, id string, status string) (int64, error)
+	GetAll(ctx context.Context, filter repository.OrderFilter) ([]*model.Order, error)
+	GetPage(ctx context.Context, filter repository.OrderFilter, page int64, size int64) ([]*model.Order, error)
+	Export(ctx context.Context, filter repository.OrderFilter) (repository.IOrderIterator, error)
+	ChangeStatus(ctx context.Context, id string, change model.StatusChange, cond repository.UpdateCondition) (int64, error)
+	ChangeStatusBulk(ctx context.Context, filter repository.OrderFilter, change model.StatusChange) ([]model.StatusChangeResult, error)
+	GetHistory(ctx context.Context, id string) ([]model.StatusChange, error)
 	Count(ctx context.Context) (int64, error)
+	CountFilter(ctx context.Context, filter repository.OrderFilter) (int64, error)
 }
 
 // OrderService instance
 type OrderService struct {
-	repository repository.IOrderRepository
-	uuid       utils.IUUIDGenerator
-	date       utils.IDateGenerator
-	logger     log.Logger
+	repository   repository.IOrderRepository
+	uuid         utils.IUUIDGenerator
+	date         utils.IDateGenerator
+	logger       log.Logger
+	maxBatchSize int
+	pipeline     []Step
+}
+
+// Option configures the Order service
+type Option func(*OrderService)
+
+// WithMaxBatchSize sets the maximum number of orders of a batch
+func WithMaxBatchSize(size int) Option {
+	return func(s *OrderService) {
+		s.maxBatchSize = size
+	}
 }
 
 // NewOrderService creates and returns a new Order service instance
 func NewOrderService(rep repository.IOrderRepository, uuid utils.IUUIDGenerator,
-	date utils.IDateGenerator, logger log.Logger) IOrderService {
-	return &OrderService{
-		repository: rep,
-		uuid:       uuid,
-		date:       date,
-		logger:     logger,
+	date utils.IDateGenerator, logger log.Logger, options ...Option) IOrderService {
+	s := &OrderService{
+		repository:   rep,
+		uuid:         uuid,
+		date:         date,
+		logger:       logger,
+		maxBatchSize: DefaultMaxBatchSize,
+		pipeline:     DefaultPipeline(),
+	}
+	for _, option := range options {
+		option(s)
 	}
+	return s
 }
 
 // Create makes an order
 func (s *OrderService) Create(ctx context.Context, order model.Order) (string, error) {
-	logger := log.With(s.logger, "method", "Create")
-	id := s.uuid.GenerateID()
-	order.ID = id
-	order.Status = "Pending"
-	order.CreatedOn = s.date.NowTimestamp()
-	created, err := s.repository.CreateOrder(ctx, order)
-	if err := validator.Validate(order); err != nil {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "Create")
+	order, err := runPipeline(ctx, s.pipeline, s.newOrder(order))
+	if err != nil {
 		level.Debug(logger).Log("err", err)
 		return "", err
 	}
+	created, err := s.repository.CreateOrder(ctx, order)
 	if err != nil {
 		level.Error(logger).Log("err", err)
 		return "", err
@@ -60,9 +94,116 @@ func (s *OrderService) Create(ctx context.Context, order model.Order) (string, e
 	return created, nil
 }
 
+// CreateBatch makes every valid order of the batch, returning the outcome of each order at its index
+func (s *OrderService) CreateBatch(ctx context.Context, orders []model.Order) ([]model.CreateResult, error) {
+	return s.insertBatch(ctx, log.With(utils.LoggerFromContext(ctx, s.logger), "method", "CreateBatch"), orders, s.newOrder)
+}
+
+// Import stores a batch of orders coming from another system, keeping their ids,
+// statuses and timestamps when present, and returns the outcome of each order at its index
+func (s *OrderService) Import(ctx context.Context, orders []model.Order) ([]model.CreateResult, error) {
+	return s.insertBatch(ctx, log.With(utils.LoggerFromContext(ctx, s.logger), "method", "Import"), orders, s.importedOrder)
+}
+
+// insertBatch prepares each order of a batch, runs the pipeline on it and inserts the ones passing it
+func (s *OrderService) insertBatch(ctx context.Context, logger log.Logger, orders []model.Order,
+	prepare func(model.Order) model.Order) ([]model.CreateResult, error) {
+	if len(orders) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if s.maxBatchSize > 0 && len(orders) > s.maxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]model.CreateResult, len(orders))
+	valid := make([]model.Order, 0, len(orders))
+	positions := make([]int, 0, len(orders))
+	for i := range orders {
+		order, err := runPipeline(ctx, s.pipeline, prepare(orders[i]))
+		if err != nil {
+			level.Debug(logger).Log("index", i, "err", err)
+			results[i].Err = err
+			continue
+		}
+		results[i].ID = order.ID
+		valid = append(valid, order)
+		positions = append(positions, i)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	errs, err := s.repository.CreateOrders(ctx, valid)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		return nil, err
+	}
+	for i, err := range errs {
+		if err != nil {
+			results[positions[i]] = model.CreateResult{Err: err}
+		}
+	}
+	return results, nil
+}
+
+// importedOrder fills the values missing on an imported order
+func (s *OrderService) importedOrder(order model.Order) model.Order {
+	if order.ID == "" {
+		order.ID = s.uuid.GenerateID()
+	}
+	if order.Status == "" {
+		order.Status = model.StatusPending
+	}
+	if order.CreatedOn == 0 {
+		order.CreatedOn = s.date.NowTimestamp()
+	}
+	if order.Version == 0 {
+		order.Version = 1
+	}
+	if len(order.StatusHistory) == 0 {
+		order.StatusHistory = importedHistory(order)
+	}
+	return order
+}
+
+// importedHistory rebuilds the status history of an imported order from its lifecycle timestamps
+func importedHistory(order model.Order) []model.StatusChange {
+	history := []model.StatusChange{
+		{Status: model.StatusPending, Timestamp: order.CreatedOn},
+	}
+	lifecycle := []model.StatusChange{
+		{Status: model.StatusAccepted, Timestamp: order.AcceptedOn},
+		{Status: model.StatusPreparing, Timestamp: order.PreparingOn},
+		{Status: model.StatusDelivered, Timestamp: order.DeliveredOn},
+		{Status: model.StatusCancelled, Timestamp: order.CancelledOn},
+		{Status: model.StatusRejected, Timestamp: order.RejectedOn},
+	}
+	for _, change := range lifecycle {
+		if change.Timestamp > 0 {
+			history = append(history, change)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp < history[j].Timestamp
+	})
+	return history
+}
+
+// newOrder sets the values of an order being created
+func (s *OrderService) newOrder(order model.Order) model.Order {
+	order.ID = s.uuid.GenerateID()
+	order.Status = model.StatusPending
+	order.CreatedOn = s.date.NowTimestamp()
+	order.Version = 1
+	order.StatusHistory = []model.StatusChange{
+		{Status: order.Status, Timestamp: order.CreatedOn},
+	}
+	return order
+}
+
 // GetByID returns an order given by id
 func (s *OrderService) GetByID(ctx context.Context, id string) (model.Order, error) {
-	logger := log.With(s.logger, "method", "GetByID")
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "GetByID")
 	order, err := s.repository.GetOrderByID(ctx, id)
 	if err != nil {
 		level.Debug(logger).Log("msg", err)
@@ -72,9 +213,11 @@ func (s *OrderService) GetByID(ctx context.Context, id string) (model.Order, err
 }
 
 // ChangeStatus changes the status of an order
-func (s *OrderService) ChangeStatus(ctx context.Context, id string, status string) (int64, error) {
-	logger := log.With(s.logger, "method", "ChangeStatus")
-	changed, err := s.repository.ChangeOrderStatus(ctx, id, status)
+func (s *OrderService) ChangeStatus(ctx context.Context, id string, change model.StatusChange,
+	cond repository.UpdateCondition) (int64, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "ChangeStatus")
+	change.Timestamp = s.date.NowTimestamp()
+	changed, err := s.repository.ChangeOrderStatus(ctx, id, change, cond)
 	if err != nil && changed < 1 {
 		level.Error(logger).Log("err", err)
 		return 0, err
@@ -82,10 +225,99 @@ func (s *OrderService) ChangeStatus(ctx context.Context, id string, status strin
 	return changed, nil
 }
 
+// ChangeStatusBulk changes the status of the orders given by ids or by restaurant and status,
+// following the status transitions, and returns the outcome of each order
+func (s *OrderService) ChangeStatusBulk(ctx context.Context, filter repository.OrderFilter,
+	change model.StatusChange) ([]model.StatusChangeResult, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "ChangeStatusBulk")
+	if len(filter.IDs) == 0 && filter.RestaurantID == "" {
+		return nil, ErrBulkWithoutTarget
+	}
+	if s.maxBatchSize > 0 && len(filter.IDs) > s.maxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	orders, err := s.repository.GetAll(ctx, filter)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		return nil, err
+	}
+	change.Timestamp = s.date.NowTimestamp()
+
+	results := make([]model.StatusChangeResult, 0, len(orders)+len(filter.IDs))
+	found := make(map[string]bool, len(orders))
+	byStatus := map[string][]string{}
+	var statuses []string
+	for _, order := range orders {
+		found[order.ID] = true
+		if !model.CanTransition(order.Status, change.Status) {
+			results = append(results, model.StatusChangeResult{
+				ID:             order.ID,
+				PreviousStatus: order.Status,
+				Outcome:        model.OutcomeInvalidTransition,
+			})
+			continue
+		}
+		if _, ok := byStatus[order.Status]; !ok {
+			statuses = append(statuses, order.Status)
+		}
+		byStatus[order.Status] = append(byStatus[order.Status], order.ID)
+	}
+	for _, id := range filter.IDs {
+		if !found[id] {
+			found[id] = true
+			results = append(results, model.StatusChangeResult{ID: id, Outcome: model.OutcomeNotFound})
+		}
+	}
+
+	for _, from := range statuses {
+		ids := byStatus[from]
+		cond := repository.UpdateCondition{Status: from}
+		changed, err := s.repository.ChangeOrdersStatus(ctx, ids, change, cond)
+		if err != nil {
+			level.Error(logger).Log("err", err)
+			return nil, err
+		}
+		updated := map[string]bool{}
+		if changed < int64(len(ids)) {
+			// some orders changed their status meanwhile, find which ones took this change
+			current, err := s.repository.GetAll(ctx, repository.OrderFilter{IDs: ids, Status: change.Status})
+			if err != nil {
+				level.Error(logger).Log("err", err)
+				return nil, err
+			}
+			for _, order := range current {
+				history := order.StatusHistory
+				if len(history) > 0 && history[len(history)-1] == change {
+					updated[order.ID] = true
+				}
+			}
+		}
+		for _, id := range ids {
+			outcome := model.OutcomeUpdated
+			if changed < int64(len(ids)) && !updated[id] {
+				outcome = model.OutcomeConflict
+			}
+			results = append(results, model.StatusChangeResult{ID: id, PreviousStatus: from, Outcome: outcome})
+		}
+	}
+	return results, nil
+}
+
+// GetHistory returns the status history of an order
+func (s *OrderService) GetHistory(ctx context.Context, id string) ([]model.StatusChange, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "GetHistory")
+	order, err := s.repository.GetOrderByID(ctx, id)
+	if err != nil {
+		level.Debug(logger).Log("msg", err)
+		return nil, err
+	}
+	return order.StatusHistory, nil
+}
+
 // GetAll recive all orders
-func (s *OrderService) GetAll(ctx context.Context) ([]*model.Order, error) {
-	logger := log.With(s.logger, "method", "GetAll")
-	orders, err := s.repository.GetAll(ctx)
+func (s *OrderService) GetAll(ctx context.Context, filter repository.OrderFilter) ([]*model.Order, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "GetAll")
+	orders, err := s.repository.GetAll(ctx, filter)
 	if err != nil {
 		level.Debug(logger).Log("msg", err)
 		return nil, err
@@ -94,9 +326,10 @@ func (s *OrderService) GetAll(ctx context.Context) ([]*model.Order, error) {
 }
 
 // GetPage returns paged orders
-func (s *OrderService) GetPage(ctx context.Context, page int64, size int64) ([]*model.Order, error) {
-	logger := log.With(s.logger, "method", "GetPage")
-	orders, err := s.repository.GetPage(ctx, page, size)
+func (s *OrderService) GetPage(ctx context.Context, filter repository.OrderFilter,
+	page int64, size int64) ([]*model.Order, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "GetPage")
+	orders, err := s.repository.GetPage(ctx, filter, page, size)
 	if err != nil {
 		level.Debug(logger).Log("msg", err)
 		return nil, err
@@ -104,9 +337,20 @@ func (s *OrderService) GetPage(ctx context.Context, page int64, size int64) ([]*
 	return orders, nil
 }
 
+// Export returns an iterator over the orders of the filter, to stream them without loading all of them
+func (s *OrderService) Export(ctx context.Context, filter repository.OrderFilter) (repository.IOrderIterator, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "Export")
+	orders, err := s.repository.Iterate(ctx, filter)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		return nil, err
+	}
+	return orders, nil
+}
+
 // Count returns the coutn of documents
 func (s *OrderService) Count(ctx context.Context) (int64, error) {
-	logger := log.With(s.logger, "method", "Count")
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "Count")
 	counted, err := s.repository.Count(ctx)
 	if err != nil {
 		level.Error(logger).Log("msg", err)
@@ -114,3 +358,14 @@ func (s *OrderService) Count(ctx context.Context) (int64, error) {
 	}
 	return counted, nil
 }
+
+// CountFilter returns the count of the orders of the filter
+func (s *OrderService) CountFilter(ctx context.Context, filter repository.OrderFilter) (int64, error) {
+	logger := log.With(utils.LoggerFromContext(ctx, s.logger), "method", "CountFilter")
+	counted, err := s.repository.CountFilter(ctx, filter)
+	if err != nil {
+		level.Error(logger).Log("msg", err)
+		return -1, err
+	}
+	return counted, nil
+}
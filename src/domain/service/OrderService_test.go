@@ -12,6 +12,7 @@ import (
 	"gotest.tools/assert"
 
 	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
 	"microservice_gokit_base/src/infraestructure/repository"
 	"microservice_gokit_base/src/mocks"
 )
@@ -41,6 +42,7 @@ func TestDomainOrderService(t *testing.T) {
 			uuid:       uuidGen,
 			date:       dateGen,
 			logger:     logger,
+			pipeline:   DefaultPipeline(),
 		}
 		ctx   = context.TODO()
 		order = model.Order{
@@ -48,17 +50,23 @@ func TestDomainOrderService(t *testing.T) {
 			Status:       "Pending",
 			RestaurantID: "EL MAGIO",
 			OrderItems: []model.OrderItem{
-				{},
+				{ProductCode: "P1", Name: "Pizza", UnitPrice: 10, Quantity: 1},
 			},
 		}
+		orderCreated  = order
 		orderEmpty    = model.Order{}
 		numberOfItems = int64(2)
 		errorCount    = int64(-1)
 		page          = int64(1)
 		size          = int64(50)
+		filter        = domainRepo.OrderFilter{RestaurantID: "EL MAGIO"}
 		listOrders    = []*model.Order{&order, &order}
 		mockError     = errors.New("errors")
 	)
+	orderCreated.Version = 1
+	orderCreated.StatusHistory = []model.StatusChange{
+		{Status: "Pending", Timestamp: 0},
+	}
 
 	t.Run("orderService.Create",
 		func(t *testing.T) {
@@ -70,7 +78,7 @@ func TestDomainOrderService(t *testing.T) {
 						orderRepository.EXPECT().CreateOrder(
 							ctx,
 							//gomock.AssignableToTypeOf(order)).Return(order.ID, nil).Times(1)
-							order).Return(order.ID, nil).Times(1),
+							orderCreated).Return(order.ID, nil).Times(1),
 					)
 
 					id, err := orderService.Create(ctx, order)
@@ -88,6 +96,90 @@ func TestDomainOrderService(t *testing.T) {
 					assert.Assert(t, id == "")
 					assert.ErrorContains(t, err, "error on the mongo repository")
 				})
+			t.Run("WHEN the order is invalid SHOULD not persist it",
+				func(t *testing.T) {
+					uuidGen.EXPECT().GenerateID().Return(order.ID).Times(1)
+					dateGen.EXPECT().NowTimestamp().Return(int64(0)).Times(1)
+					id, err := orderService.Create(ctx, model.Order{})
+					assert.Assert(t, id == "")
+					_, ok := err.(ValidationError)
+					assert.Assert(t, ok)
+				})
+		})
+
+	t.Run("orderService.CreateBatch",
+		func(t *testing.T) {
+			invalid := model.Order{}
+
+			t.Run("WHEN some orders are invalid SHOULD create only the valid ones",
+				func(t *testing.T) {
+					uuidGen.EXPECT().GenerateID().Return(order.ID).Times(2)
+					dateGen.EXPECT().NowTimestamp().Return(int64(0)).Times(2)
+					orderRepository.EXPECT().CreateOrders(
+						ctx,
+						[]model.Order{orderCreated}).Return([]error{nil}, nil).Times(1)
+
+					results, err := orderService.CreateBatch(ctx, []model.Order{invalid, order})
+					assert.NilError(t, err)
+					assert.Assert(t, len(results) == 2)
+					assert.Assert(t, results[0].Err != nil)
+					assert.Assert(t, results[0].ID == "")
+					assert.NilError(t, results[1].Err)
+					assert.Assert(t, results[1].ID == order.ID)
+				})
+			t.Run("WHEN the repository rejects an order SHOULD return its error",
+				func(t *testing.T) {
+					uuidGen.EXPECT().GenerateID().Return(order.ID).Times(1)
+					dateGen.EXPECT().NowTimestamp().Return(int64(0)).Times(1)
+					orderRepository.EXPECT().CreateOrders(
+						ctx,
+						[]model.Order{orderCreated}).Return([]error{mockError}, nil).Times(1)
+
+					results, err := orderService.CreateBatch(ctx, []model.Order{order})
+					assert.NilError(t, err)
+					assert.Assert(t, results[0].Err == mockError)
+					assert.Assert(t, results[0].ID == "")
+				})
+			t.Run("WHEN the batch is empty or too large SHOULD return an error",
+				func(t *testing.T) {
+					_, err := orderService.CreateBatch(ctx, nil)
+					assert.Assert(t, err == ErrEmptyBatch)
+
+					limited := *orderService
+					limited.maxBatchSize = 1
+					_, err = limited.CreateBatch(ctx, []model.Order{order, order})
+					assert.Assert(t, err == ErrBatchTooLarge)
+				})
+		})
+
+	t.Run("orderService.Import",
+		func(t *testing.T) {
+			t.Run("WHEN the orders have ids and timestamps SHOULD keep them",
+				func(t *testing.T) {
+					imported := model.Order{
+						ID:           "legacy-1",
+						Status:       model.StatusDelivered,
+						CreatedOn:    10,
+						AcceptedOn:   20,
+						DeliveredOn:  30,
+						RestaurantID: "EL MAGIO",
+						OrderItems:   []model.OrderItem{{ProductCode: "P1", Quantity: 1}},
+					}
+					expected := imported
+					expected.Version = 1
+					expected.StatusHistory = []model.StatusChange{
+						{Status: model.StatusPending, Timestamp: 10},
+						{Status: model.StatusAccepted, Timestamp: 20},
+						{Status: model.StatusDelivered, Timestamp: 30},
+					}
+					orderRepository.EXPECT().CreateOrders(
+						ctx,
+						[]model.Order{expected}).Return([]error{nil}, nil).Times(1)
+
+					results, err := orderService.Import(ctx, []model.Order{imported})
+					assert.NilError(t, err)
+					assert.DeepEqual(t, results, []model.CreateResult{{ID: "legacy-1"}})
+				})
 		})
 
 	t.Run("orderService.GetPage",
@@ -96,10 +188,11 @@ func TestDomainOrderService(t *testing.T) {
 				func(t *testing.T) {
 					orderRepository.EXPECT().GetPage(
 						ctx,
+						filter,
 						page,
 						size).Return(listOrders, nil).Times(1)
 
-					list, err := orderService.GetPage(ctx, page, size)
+					list, err := orderService.GetPage(ctx, filter, page, size)
 					assert.NilError(t, err)
 					assert.Assert(t, len(list) == 2)
 				})
@@ -107,10 +200,11 @@ func TestDomainOrderService(t *testing.T) {
 				func(t *testing.T) {
 					orderRepository.EXPECT().GetPage(
 						ctx,
+						filter,
 						page,
 						size).Return(nil, repository.ErrMongoRepository).Times(1)
 
-					list, err := orderService.GetPage(ctx, page, size)
+					list, err := orderService.GetPage(ctx, filter, page, size)
 					assert.Assert(t, err == repository.ErrMongoRepository)
 					assert.Assert(t, list == nil)
 				})
@@ -118,10 +212,11 @@ func TestDomainOrderService(t *testing.T) {
 				func(t *testing.T) {
 					orderRepository.EXPECT().GetPage(
 						ctx,
+						filter,
 						page,
 						size).Return(nil, repository.ErrMongoRepository).Times(1)
 
-					list, err := orderService.GetPage(ctx, page, size)
+					list, err := orderService.GetPage(ctx, filter, page, size)
 					assert.Assert(t, err == repository.ErrMongoRepository)
 					assert.Assert(t, list == nil)
 				})
@@ -187,10 +282,10 @@ func TestDomainOrderService(t *testing.T) {
 				func(t *testing.T) {
 					gomock.InOrder(
 						orderRepository.EXPECT().GetAll(
-							ctx).Return(listOrders, nil).Times(1),
+							ctx, filter).Return(listOrders, nil).Times(1),
 					)
 
-					orders, err := orderService.GetAll(ctx)
+					orders, err := orderService.GetAll(ctx, filter)
 					assert.NilError(t, err)
 					assert.Assert(t, len(orders) == len(listOrders))
 				})
@@ -199,42 +294,148 @@ func TestDomainOrderService(t *testing.T) {
 				func(t *testing.T) {
 					gomock.InOrder(
 						orderRepository.EXPECT().GetAll(
-							ctx).Return(nil, mockError).Times(1),
+							ctx, filter).Return(nil, mockError).Times(1),
 					)
 
-					_, err := orderService.GetAll(ctx)
+					_, err := orderService.GetAll(ctx, filter)
+					assert.Assert(t, err == mockError)
+				})
+		})
+
+	t.Run("orderService.Export",
+		func(t *testing.T) {
+			t.Run("WHEN an error happend SHOULD return an error",
+				func(t *testing.T) {
+					orderRepository.EXPECT().Iterate(ctx, filter).Return(nil, mockError).Times(1)
+
+					orders, err := orderService.Export(ctx, filter)
 					assert.Assert(t, err == mockError)
+					assert.Assert(t, orders == nil)
 				})
 		})
 
 	t.Run("orderService.ChangeStatus",
 		func(t *testing.T) {
-			partialUpdate := "disabled"
+			partialUpdate := model.StatusChange{
+				Status: "disabled",
+				Actor:  "kitchen",
+			}
+			cond := domainRepo.UpdateCondition{Version: 2, CheckVersion: true}
+			timestamp := int64(100)
+			expectedChange := partialUpdate
+			expectedChange.Timestamp = timestamp
 
 			t.Run("WHEN everything is ok SHOULD return a number of correct changes status",
 				func(t *testing.T) {
 					gomock.InOrder(
+						dateGen.EXPECT().NowTimestamp().Return(timestamp).Times(1),
 						orderRepository.EXPECT().ChangeOrderStatus(
 							ctx,
 							order.ID,
-							partialUpdate).Return(int64(1), nil).Times(1),
+							expectedChange,
+							cond).Return(int64(1), nil).Times(1),
 					)
-					statusCount, err := orderService.ChangeStatus(ctx, order.ID, partialUpdate)
+					statusCount, err := orderService.ChangeStatus(ctx, order.ID, partialUpdate, cond)
 					assert.NilError(t, err)
 					assert.Assert(t, statusCount == 1)
 				})
 			t.Run("WHEN an error happend on the repository SHOULD return an error",
 				func(t *testing.T) {
 					gomock.InOrder(
+						dateGen.EXPECT().NowTimestamp().Return(timestamp).Times(1),
 						orderRepository.EXPECT().ChangeOrderStatus(
 							ctx,
 							order.ID,
-							partialUpdate).Return(int64(0), repository.ErrMongoRepository).Times(1),
+							expectedChange,
+							cond).Return(int64(0), repository.ErrMongoRepository).Times(1),
 					)
 
-					statusCount, err := orderService.ChangeStatus(ctx, order.ID, partialUpdate)
+					statusCount, err := orderService.ChangeStatus(ctx, order.ID, partialUpdate, cond)
 					assert.Assert(t, err == repository.ErrMongoRepository)
 					assert.Assert(t, statusCount == 0)
 				})
 		})
+
+	t.Run("orderService.ChangeStatusBulk",
+		func(t *testing.T) {
+			timestamp := int64(100)
+			change := model.StatusChange{Status: model.StatusCancelled, Actor: "kitchen"}
+			expectedChange := change
+			expectedChange.Timestamp = timestamp
+			pending := model.Order{ID: "1", Status: model.StatusPending}
+			preparing := model.Order{ID: "2", Status: model.StatusPreparing}
+			delivered := model.Order{ID: "3", Status: model.StatusDelivered}
+
+			t.Run("WHEN the orders are given by ids SHOULD report the outcome of each one",
+				func(t *testing.T) {
+					filter := domainRepo.OrderFilter{IDs: []string{"1", "2", "3", "4"}}
+					gomock.InOrder(
+						orderRepository.EXPECT().GetAll(ctx, filter).Return(
+							[]*model.Order{&pending, &preparing, &delivered}, nil).Times(1),
+						dateGen.EXPECT().NowTimestamp().Return(timestamp).Times(1),
+						orderRepository.EXPECT().ChangeOrdersStatus(ctx, []string{"1"}, expectedChange,
+							domainRepo.UpdateCondition{Status: model.StatusPending}).Return(int64(1), nil).Times(1),
+						orderRepository.EXPECT().ChangeOrdersStatus(ctx, []string{"2"}, expectedChange,
+							domainRepo.UpdateCondition{Status: model.StatusPreparing}).Return(int64(0), nil).Times(1),
+						orderRepository.EXPECT().GetAll(ctx, domainRepo.OrderFilter{
+							IDs:    []string{"2"},
+							Status: model.StatusCancelled,
+						}).Return(nil, nil).Times(1),
+					)
+
+					results, err := orderService.ChangeStatusBulk(ctx, filter, change)
+					assert.NilError(t, err)
+					assert.DeepEqual(t, results, []model.StatusChangeResult{
+						{ID: "3", PreviousStatus: model.StatusDelivered, Outcome: model.OutcomeInvalidTransition},
+						{ID: "4", Outcome: model.OutcomeNotFound},
+						{ID: "1", PreviousStatus: model.StatusPending, Outcome: model.OutcomeUpdated},
+						{ID: "2", PreviousStatus: model.StatusPreparing, Outcome: model.OutcomeConflict},
+					})
+				})
+			t.Run("WHEN there are neither ids nor restaurant SHOULD return an error",
+				func(t *testing.T) {
+					_, err := orderService.ChangeStatusBulk(ctx, domainRepo.OrderFilter{Status: model.StatusPending}, change)
+					assert.Assert(t, err == ErrBulkWithoutTarget)
+				})
+			t.Run("WHEN an error happend on the repository SHOULD return an error",
+				func(t *testing.T) {
+					filter := domainRepo.OrderFilter{RestaurantID: "EL MAGIO"}
+					orderRepository.EXPECT().GetAll(ctx, filter).Return(nil, mockError).Times(1)
+
+					results, err := orderService.ChangeStatusBulk(ctx, filter, change)
+					assert.Assert(t, err == mockError)
+					assert.Assert(t, results == nil)
+				})
+		})
+
+	t.Run("orderService.GetHistory",
+		func(t *testing.T) {
+
+			t.Run("WHEN everything is ok SHOULD return the status history",
+				func(t *testing.T) {
+					gomock.InOrder(
+						orderRepository.EXPECT().GetOrderByID(
+							ctx,
+							order.ID).Return(orderCreated, nil).Times(1),
+					)
+
+					history, err := orderService.GetHistory(ctx, order.ID)
+					assert.NilError(t, err)
+					assert.Assert(t, len(history) == 1)
+					assert.Assert(t, history[0].Status == "Pending")
+				})
+
+			t.Run("WHEN an error happend SHOULD return an error",
+				func(t *testing.T) {
+					gomock.InOrder(
+						orderRepository.EXPECT().GetOrderByID(
+							ctx,
+							order.ID).Return(orderEmpty, mockError).Times(1),
+					)
+
+					history, err := orderService.GetHistory(ctx, order.ID)
+					assert.Assert(t, err == mockError)
+					assert.Assert(t, history == nil)
+				})
+		})
 }
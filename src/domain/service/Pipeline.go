@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"microservice_gokit_base/src/domain/model"
+)
+
+// Step prepares or checks an order before it is persisted, an error stops the order
+type Step interface {
+	Apply(ctx context.Context, order model.Order) (model.Order, error)
+}
+
+// StepFunc adapts a function to a Step
+type StepFunc func(ctx context.Context, order model.Order) (model.Order, error)
+
+// Apply calls the function
+func (f StepFunc) Apply(ctx context.Context, order model.Order) (model.Order, error) {
+	return f(ctx, order)
+}
+
+// WithPipeline replaces the steps run on every order before it is persisted
+func WithPipeline(steps ...Step) Option {
+	return func(s *OrderService) {
+		s.pipeline = steps
+	}
+}
+
+// DefaultPipeline normalizes and validates the orders
+func DefaultPipeline() []Step {
+	return []Step{NormalizeStep(), ValidateStep()}
+}
+
+// runPipeline applies the steps in order, stopping at the first error
+func runPipeline(ctx context.Context, steps []Step, order model.Order) (model.Order, error) {
+	for _, step := range steps {
+		var err error
+		if order, err = step.Apply(ctx, order); err != nil {
+			return model.Order{}, err
+		}
+	}
+	return order, nil
+}
+
+// NormalizeStep trims the spaces around the identifiers and names of the order
+func NormalizeStep() Step {
+	return StepFunc(func(_ context.Context, order model.Order) (model.Order, error) {
+		order.CustomerID = strings.TrimSpace(order.CustomerID)
+		order.RestaurantID = strings.TrimSpace(order.RestaurantID)
+		items := make([]model.OrderItem, len(order.OrderItems))
+		for i, item := range order.OrderItems {
+			item.ProductCode = strings.TrimSpace(item.ProductCode)
+			item.Name = strings.TrimSpace(item.Name)
+			items[i] = item
+		}
+		if order.OrderItems != nil {
+			order.OrderItems = items
+		}
+		return order, nil
+	})
+}
+
+// ValidateStep checks the validate rules of the order
+func ValidateStep() Step {
+	return StepFunc(func(_ context.Context, order model.Order) (model.Order, error) {
+		return order, validateOrder(order)
+	})
+}
+
+// IPriceList gives the current price of the products of a restaurant
+type IPriceList interface {
+	Price(ctx context.Context, restaurantID string, productCode string) (float32, bool, error)
+}
+
+// PricingStep sets the unit price of every item from the price list, rejecting
+// the products the list does not know
+func PricingStep(prices IPriceList) Step {
+	return StepFunc(func(ctx context.Context, order model.Order) (model.Order, error) {
+		items := make([]model.OrderItem, len(order.OrderItems))
+		var fields []FieldError
+		for i, item := range order.OrderItems {
+			price, ok, err := prices.Price(ctx, order.RestaurantID, item.ProductCode)
+			if err != nil {
+				return model.Order{}, err
+			}
+			if !ok {
+				fields = append(fields, FieldError{
+					Field:    "product_code",
+					JSONPath: fmt.Sprintf("$.order_items[%d].product_code", i),
+					Rule:     "priced",
+					Message:  "is not sold by the restaurant",
+				})
+			}
+			item.UnitPrice = price
+			items[i] = item
+		}
+		if len(fields) > 0 {
+			return model.Order{}, ValidationError{Fields: fields}
+		}
+		order.OrderItems = items
+		return order, nil
+	})
+}
+
+// PolicyError when an order is rejected by a policy of the deployment
+type PolicyError struct {
+	Policy string
+	Reason string
+}
+
+func (e PolicyError) Error() string {
+	return fmt.Sprintf("the order breaks the %s policy: %s", e.Policy, e.Reason)
+}
+
+// Policy checks a business rule on an order, returning a PolicyError when broken
+type Policy func(ctx context.Context, order model.Order) error
+
+// PolicyStep checks every policy on the order
+func PolicyStep(policies ...Policy) Step {
+	return StepFunc(func(ctx context.Context, order model.Order) (model.Order, error) {
+		for _, policy := range policies {
+			if err := policy(ctx, order); err != nil {
+				return model.Order{}, err
+			}
+		}
+		return order, nil
+	})
+}
+
+// MaxOrderTotal rejects the orders whose items cost more than max
+func MaxOrderTotal(max float32) Policy {
+	return func(_ context.Context, order model.Order) error {
+		var total float32
+		for _, item := range order.OrderItems {
+			total += item.UnitPrice * float32(item.Quantity)
+		}
+		if total > max {
+			return PolicyError{
+				Policy: "max_order_total",
+				Reason: fmt.Sprintf("the total %.2f is greater than %.2f", total, max),
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"microservice_gokit_base/src/domain/model"
+
+	"gotest.tools/assert"
+)
+
+type priceListStub map[string]float32
+
+func (p priceListStub) Price(_ context.Context, _ string, productCode string) (float32, bool, error) {
+	if productCode == "ERR" {
+		return 0, false, errors.New("price list unavailable")
+	}
+	price, ok := p[productCode]
+	return price, ok, nil
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.TODO()
+	order := model.Order{
+		RestaurantID: "EL MAGIO",
+		OrderItems: []model.OrderItem{
+			{ProductCode: "P1", Name: "Pizza", UnitPrice: 10, Quantity: 2},
+		},
+	}
+
+	t.Run("runPipeline", func(t *testing.T) {
+		t.Run("WHEN every step passes SHOULD return the order of the last step", func(t *testing.T) {
+			var calls []string
+			step := func(name string) Step {
+				return StepFunc(func(_ context.Context, o model.Order) (model.Order, error) {
+					calls = append(calls, name)
+					o.CustomerID += name
+					return o, nil
+				})
+			}
+			result, err := runPipeline(ctx, []Step{step("a"), step("b")}, order)
+			assert.NilError(t, err)
+			assert.Equal(t, result.CustomerID, "ab")
+			assert.DeepEqual(t, calls, []string{"a", "b"})
+		})
+		t.Run("WHEN a step fails SHOULD stop the pipeline", func(t *testing.T) {
+			failure := errors.New("failure")
+			called := false
+			_, err := runPipeline(ctx, []Step{
+				StepFunc(func(context.Context, model.Order) (model.Order, error) { return model.Order{}, failure }),
+				StepFunc(func(_ context.Context, o model.Order) (model.Order, error) { called = true; return o, nil }),
+			}, order)
+			assert.Equal(t, err, failure)
+			assert.Assert(t, !called)
+		})
+	})
+
+	t.Run("NormalizeStep", func(t *testing.T) {
+		t.Run("WHEN the fields have spaces SHOULD trim them without changing the input", func(t *testing.T) {
+			input := model.Order{
+				CustomerID:   " C1 ",
+				RestaurantID: "\tEL MAGIO ",
+				OrderItems:   []model.OrderItem{{ProductCode: " P1", Name: "Pizza "}},
+			}
+			result, err := NormalizeStep().Apply(ctx, input)
+			assert.NilError(t, err)
+			assert.Equal(t, result.CustomerID, "C1")
+			assert.Equal(t, result.RestaurantID, "EL MAGIO")
+			assert.DeepEqual(t, result.OrderItems, []model.OrderItem{{ProductCode: "P1", Name: "Pizza"}})
+			assert.Equal(t, input.OrderItems[0].ProductCode, " P1")
+		})
+	})
+
+	t.Run("ValidateStep", func(t *testing.T) {
+		t.Run("WHEN the order is valid SHOULD return it", func(t *testing.T) {
+			result, err := ValidateStep().Apply(ctx, order)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, result, order)
+		})
+		t.Run("WHEN the order is invalid SHOULD return a validation error", func(t *testing.T) {
+			_, err := ValidateStep().Apply(ctx, model.Order{})
+			_, ok := err.(ValidationError)
+			assert.Assert(t, ok)
+		})
+	})
+
+	t.Run("PricingStep", func(t *testing.T) {
+		prices := priceListStub{"P1": 12.5}
+		t.Run("WHEN the products are known SHOULD set their prices", func(t *testing.T) {
+			result, err := PricingStep(prices).Apply(ctx, order)
+			assert.NilError(t, err)
+			assert.Equal(t, result.OrderItems[0].UnitPrice, float32(12.5))
+			assert.Equal(t, order.OrderItems[0].UnitPrice, float32(10))
+		})
+		t.Run("WHEN a product is unknown SHOULD return its path", func(t *testing.T) {
+			unknown := order
+			unknown.OrderItems = append([]model.OrderItem{order.OrderItems[0]}, model.OrderItem{ProductCode: "P9", Quantity: 1})
+			_, err := PricingStep(prices).Apply(ctx, unknown)
+			assert.DeepEqual(t, err, ValidationError{Fields: []FieldError{
+				{Field: "product_code", JSONPath: "$.order_items[1].product_code", Rule: "priced", Message: "is not sold by the restaurant"},
+			}})
+		})
+		t.Run("WHEN the price list fails SHOULD return its error", func(t *testing.T) {
+			failing := order
+			failing.OrderItems = []model.OrderItem{{ProductCode: "ERR", Quantity: 1}}
+			_, err := PricingStep(prices).Apply(ctx, failing)
+			assert.Error(t, err, "price list unavailable")
+		})
+	})
+
+	t.Run("PolicyStep", func(t *testing.T) {
+		t.Run("WHEN the total is under the max SHOULD return the order", func(t *testing.T) {
+			result, err := PolicyStep(MaxOrderTotal(20)).Apply(ctx, order)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, result, order)
+		})
+		t.Run("WHEN the total is over the max SHOULD return a policy error", func(t *testing.T) {
+			_, err := PolicyStep(MaxOrderTotal(19.99)).Apply(ctx, order)
+			assert.DeepEqual(t, err, PolicyError{
+				Policy: "max_order_total",
+				Reason: "the total 20.00 is greater than 19.99",
+			})
+		})
+	})
+}
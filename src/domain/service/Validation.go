@@ -0,0 +1,131 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"microservice_gokit_base/src/domain/model"
+
+	"gopkg.in/validator.v2"
+)
+
+// FieldError describes a rule broken by a field of the order
+type FieldError struct {
+	Field    string `json:"field"`
+	JSONPath string `json:"json_path"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// ValidationError when an order breaks the validation rules
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = strings.TrimPrefix(f.JSONPath, "$.") + " " + f.Message
+	}
+	return "the order is invalid: " + strings.Join(messages, "; ")
+}
+
+// validateOrder checks the validate tags of the order, naming the broken fields
+// as they are on its JSON encoding
+func validateOrder(order model.Order) error {
+	err := validator.Validate(order)
+	if err == nil {
+		return nil
+	}
+	errMap, ok := err.(validator.ErrorMap)
+	if !ok {
+		return err
+	}
+
+	var fields []FieldError
+	for path, errs := range errMap {
+		field, jsonPath, tag := resolveField(reflect.TypeOf(order), path)
+		for _, e := range errs {
+			rule, message := describeRule(e, tag, field.Type.Kind())
+			fields = append(fields, FieldError{
+				Field:    jsonPath[strings.LastIndex(jsonPath, ".")+1:],
+				JSONPath: "$." + jsonPath,
+				Rule:     rule,
+				Message:  message,
+			})
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].JSONPath < fields[j].JSONPath
+	})
+	return ValidationError{Fields: fields}
+}
+
+// resolveField walks a validator path like OrderItems[0].Quantity, returning the
+// field it ends at, its JSON path and its validate tag
+func resolveField(t reflect.Type, path string) (reflect.StructField, string, string) {
+	var field reflect.StructField
+	var jsonPath []string
+	for _, segment := range strings.Split(path, ".") {
+		name, index := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		f, ok := t.FieldByName(name)
+		if !ok {
+			return reflect.StructField{Name: name, Type: reflect.TypeOf("")}, path, ""
+		}
+		field, t = f, f.Type
+		jsonPath = append(jsonPath, jsonFieldName(f)+index)
+	}
+	return field, strings.Join(jsonPath, "."), field.Tag.Get("validate")
+}
+
+// jsonFieldName returns the name of the field on the JSON encoding
+func jsonFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+// describeRule names the rule of the validate tag broken by err
+func describeRule(err error, tag string, kind reflect.Kind) (string, string) {
+	switch err {
+	case validator.ErrZeroValue:
+		return "nonzero", "is required"
+	case validator.ErrMin:
+		limit := tagParam(tag, "min")
+		if kind == reflect.Slice || kind == reflect.Array {
+			return "min", "must have at least " + limit + " items"
+		}
+		return "min", "must be at least " + limit
+	case validator.ErrMax:
+		limit := tagParam(tag, "max")
+		if kind == reflect.Slice || kind == reflect.Array {
+			return "max", "must have at most " + limit + " items"
+		}
+		return "max", "must be at most " + limit
+	case validator.ErrLen:
+		return "len", "must have a length of " + tagParam(tag, "len")
+	case validator.ErrRegexp:
+		return "regexp", "does not match the expected format"
+	}
+	return "invalid", err.Error()
+}
+
+// tagParam returns the parameter of a rule on a validate tag
+func tagParam(tag string, rule string) string {
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, rule+"=") {
+			return strings.TrimPrefix(part, rule+"=")
+		}
+	}
+	return ""
+}
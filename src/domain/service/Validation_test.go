@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+
+	"microservice_gokit_base/src/domain/model"
+
+	"gotest.tools/assert"
+)
+
+func TestValidateOrder(t *testing.T) {
+	valid := model.Order{
+		RestaurantID: "EL MAGIO",
+		OrderItems: []model.OrderItem{
+			{ProductCode: "P1", Name: "Pizza", UnitPrice: 10, Quantity: 2},
+		},
+	}
+
+	t.Run("WHEN the order is valid SHOULD return nil", func(t *testing.T) {
+		assert.NilError(t, validateOrder(valid))
+	})
+	t.Run("WHEN the order has no restaurant and items SHOULD name the json fields", func(t *testing.T) {
+		err := validateOrder(model.Order{})
+		assert.DeepEqual(t, err, ValidationError{Fields: []FieldError{
+			{Field: "order_items", JSONPath: "$.order_items", Rule: "nonzero", Message: "is required"},
+			{Field: "order_items", JSONPath: "$.order_items", Rule: "min", Message: "must have at least 1 items"},
+			{Field: "restaurant_id", JSONPath: "$.restaurant_id", Rule: "nonzero", Message: "is required"},
+		}})
+	})
+	t.Run("WHEN an item breaks the rules SHOULD return the path of the item", func(t *testing.T) {
+		order := valid
+		order.OrderItems = []model.OrderItem{
+			valid.OrderItems[0],
+			{Name: "Soda", UnitPrice: -1, Quantity: 0},
+		}
+		err := validateOrder(order)
+		assert.DeepEqual(t, err, ValidationError{Fields: []FieldError{
+			{Field: "product_code", JSONPath: "$.order_items[1].product_code", Rule: "nonzero", Message: "is required"},
+			{Field: "quantity", JSONPath: "$.order_items[1].quantity", Rule: "min", Message: "must be at least 1"},
+			{Field: "unit_price", JSONPath: "$.order_items[1].unit_price", Rule: "min", Message: "must be at least 0"},
+		}})
+		assert.Error(t, err, "the order is invalid: order_items[1].product_code is required; "+
+			"order_items[1].quantity must be at least 1; order_items[1].unit_price must be at least 0")
+	})
+	t.Run("WHEN the order has too many items SHOULD return the max rule", func(t *testing.T) {
+		order := valid
+		order.OrderItems = make([]model.OrderItem, 101)
+		for i := range order.OrderItems {
+			order.OrderItems[i] = valid.OrderItems[0]
+		}
+		err := validateOrder(order)
+		assert.DeepEqual(t, err, ValidationError{Fields: []FieldError{
+			{Field: "order_items", JSONPath: "$.order_items", Rule: "max", Message: "must have at most 100 items"},
+		}})
+	})
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the sensitive values
+const Redacted = "[REDACTED]"
+
+// DefaultRedactedKeys are the keys whose values are always masked
+var DefaultRedactedKeys = []string{
+	"customer_id", "email", "phone", "address", "street", "postal_code", "zip",
+}
+
+// DefaultRedactedPatterns match the personal data found inside free text like errors,
+// the patterns with two groups keep the first one and mask the second
+var DefaultRedactedPatterns = []*regexp.Regexp{
+	// key value pairs of the sensitive keys, as written by Mongo, JSON or logfmt
+	regexp.MustCompile(`(?i)("?(?:customer_id|customerid|email|phone|address|street|postal_code|zip)"?\s*[:=]\s*)("[^"]*"|[^\s,}\]]+)`),
+	// emails
+	regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	// phone numbers written as phones: with a country code, an area code in parentheses or digits
+	// grouped by spaces or dashes, so the ids, timestamps and amounts made of digits are kept
+	regexp.MustCompile(`\+\d{1,3}(?:[\s.\-]?\(?\d{2,4}\)?){2,5}\b|\(\d{3}\)\s?\d{3}[\s\-]\d{4}\b|\b\d{3}(?:[\s\-]\d{2,4}){2,3}\b`),
+	// street addresses like "221 Baker Street" or "Calle Mayor 5"
+	regexp.MustCompile(`(?i)\b\d+\s+(?:[A-Za-z]+\s){0,3}(?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln)\b\.?`),
+	regexp.MustCompile(`(?i)\b(?:calle|avenida|av|plaza|paseo)\.?\s+(?:[A-Za-zÀ-ÿ]+\s){0,3}\d+\b`),
+}
+
+// Redactor masks the sensitive values of log lines and messages
+type Redactor struct {
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns a redactor masking the values of the keys and the matches of the patterns
+func NewRedactor(keys []string, patterns []*regexp.Regexp) *Redactor {
+	r := &Redactor{keys: make(map[string]bool, len(keys)), patterns: patterns}
+	for _, key := range keys {
+		r.keys[strings.ToLower(key)] = true
+	}
+	return r
+}
+
+// NewDefaultRedactor returns a redactor of the default keys and patterns plus the extra keys
+func NewDefaultRedactor(extraKeys ...string) *Redactor {
+	return NewRedactor(append(append([]string{}, DefaultRedactedKeys...), extraKeys...), DefaultRedactedPatterns)
+}
+
+// SensitiveKey tells if the values of the key are always masked
+func (r *Redactor) SensitiveKey(key string) bool {
+	return r.keys[strings.ToLower(key)]
+}
+
+// String masks the parts of s matching the patterns
+func (r *Redactor) String(s string) string {
+	for _, pattern := range r.patterns {
+		if pattern.NumSubexp() == 2 {
+			s = pattern.ReplaceAllString(s, "${1}"+Redacted)
+			continue
+		}
+		s = pattern.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+)
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a context carrying the id of the request
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the id of the request carried by the context, empty when none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// LoggerFromContext returns the logger adding the request id of the context to every line
+func LoggerFromContext(ctx context.Context, logger log.Logger) log.Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return log.With(logger, "request_id", id)
+	}
+	return logger
+}
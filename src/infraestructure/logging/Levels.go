@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Components of the service with their own log level
+const (
+	ComponentService    = "service"
+	ComponentRepository = "repository"
+	ComponentTransport  = "transport"
+)
+
+var (
+	// ErrUnknownLevel when the level is not debug, info, warn or error
+	ErrUnknownLevel = errors.New("the level must be debug, info, warn or error")
+	// ErrUnknownComponent when the component has no log level
+	ErrUnknownComponent = errors.New("the component has no log level")
+)
+
+// ranks orders the levels, the lines of a level are logged when it is at least the allowed one
+var ranks = map[string]int{
+	level.DebugValue().String(): 0,
+	level.InfoValue().String():  1,
+	level.WarnValue().String():  2,
+	level.ErrorValue().String(): 3,
+}
+
+// NewLogger returns a logger writing logfmt or, when format is "json", JSON lines
+func NewLogger(w io.Writer, format string) log.Logger {
+	if format == "json" {
+		return log.NewJSONLogger(w)
+	}
+	return log.NewLogfmtLogger(w)
+}
+
+// Allow returns the filter option of a level, info when it is unknown
+func Allow(lvl string) level.Option {
+	switch lvl {
+	case level.DebugValue().String():
+		return level.AllowDebug()
+	case level.WarnValue().String():
+		return level.AllowWarn()
+	case level.ErrorValue().String():
+		return level.AllowError()
+	}
+	return level.AllowInfo()
+}
+
+// Levels holds the log level of every component, the levels can be changed at runtime
+type Levels struct {
+	mu     sync.RWMutex
+	levels map[string]string
+}
+
+// NewLevels returns the components starting at the given level, info when it is unknown
+func NewLevels(initial string, components ...string) *Levels {
+	if _, ok := ranks[initial]; !ok {
+		initial = level.InfoValue().String()
+	}
+	levels := make(map[string]string, len(components))
+	for _, component := range components {
+		levels[component] = initial
+	}
+	return &Levels{levels: levels}
+}
+
+// Levels returns the current level of every component
+func (l *Levels) Levels() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	levels := make(map[string]string, len(l.levels))
+	for component, lvl := range l.levels {
+		levels[component] = lvl
+	}
+	return levels
+}
+
+// Components returns the names of the components sorted
+func (l *Levels) Components() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	components := make([]string, 0, len(l.levels))
+	for component := range l.levels {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	return components
+}
+
+// SetLevel changes the level of a component
+func (l *Levels) SetLevel(component string, lvl string) error {
+	if _, ok := ranks[lvl]; !ok {
+		return ErrUnknownLevel
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.levels[component]; !ok {
+		return ErrUnknownComponent
+	}
+	l.levels[component] = lvl
+	return nil
+}
+
+// Logger returns a logger dropping the lines under the current level of the component
+func (l *Levels) Logger(component string, next log.Logger) log.Logger {
+	return &componentLogger{next: log.With(next, "component", component), levels: l, component: component}
+}
+
+func (l *Levels) allows(component string, lvl string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	allowed, ok := ranks[l.levels[component]]
+	rank, known := ranks[lvl]
+	return !ok || !known || rank >= allowed
+}
+
+type componentLogger struct {
+	next      log.Logger
+	levels    *Levels
+	component string
+}
+
+func (c *componentLogger) Log(keyvals ...interface{}) error {
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if keyvals[i] != level.Key() {
+			continue
+		}
+		if v, ok := keyvals[i+1].(level.Value); ok && !c.levels.allows(c.component, v.String()) {
+			return nil
+		}
+	}
+	return c.next.Log(keyvals...)
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gotest.tools/assert"
+)
+
+func TestLevels(t *testing.T) {
+	t.Run("WHEN a level is changed at runtime SHOULD filter the lines of its component only", func(t *testing.T) {
+		var buf bytes.Buffer
+		levels := NewLevels("info", ComponentService, ComponentRepository)
+		service := log.With(levels.Logger(ComponentService, log.NewLogfmtLogger(&buf)), "method", "Create")
+		repository := levels.Logger(ComponentRepository, log.NewLogfmtLogger(&buf))
+
+		level.Debug(service).Log("msg", "hidden")
+		assert.Equal(t, buf.String(), "")
+
+		assert.NilError(t, levels.SetLevel(ComponentService, "debug"))
+		level.Debug(service).Log("msg", "shown")
+		level.Debug(repository).Log("msg", "hidden")
+		assert.Equal(t, buf.String(), "component=service level=debug method=Create msg=shown\n")
+
+		buf.Reset()
+		assert.NilError(t, levels.SetLevel(ComponentService, "error"))
+		level.Warn(service).Log("msg", "hidden")
+		level.Error(service).Log("msg", "shown")
+		service.Log("msg", "no level")
+		assert.Equal(t, strings.Count(buf.String(), "\n"), 2)
+	})
+	t.Run("WHEN the component or level are unknown SHOULD return an error", func(t *testing.T) {
+		levels := NewLevels("debug", ComponentService)
+		assert.Equal(t, levels.SetLevel("other", "debug"), ErrUnknownComponent)
+		assert.Equal(t, levels.SetLevel(ComponentService, "verbose"), ErrUnknownLevel)
+		assert.DeepEqual(t, levels.Levels(), map[string]string{ComponentService: "debug"})
+	})
+	t.Run("WHEN the initial level is unknown SHOULD start at info", func(t *testing.T) {
+		levels := NewLevels("", ComponentService)
+		assert.DeepEqual(t, levels.Levels(), map[string]string{ComponentService: "info"})
+	})
+	t.Run("WHEN the format is json SHOULD write json lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		NewLogger(&buf, "json").Log("msg", "hello")
+		assert.Equal(t, buf.String(), "{\"msg\":\"hello\"}\n")
+	})
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"fmt"
+
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/log"
+)
+
+// NewRedactLogger masks the values of the sensitive keys and the personal data found
+// in the values before they reach next. The values other than strings, errors, numbers
+// and booleans, as structs or maps, are formatted with their field names and replaced
+// by the masked text when it holds personal data
+func NewRedactLogger(next log.Logger, redactor *utils.Redactor) log.Logger {
+	return &redactLogger{next: next, redactor: redactor}
+}
+
+type redactLogger struct {
+	next     log.Logger
+	redactor *utils.Redactor
+}
+
+func (l *redactLogger) Log(keyvals ...interface{}) error {
+	redacted := make([]interface{}, len(keyvals))
+	copy(redacted, keyvals)
+	for i := 1; i < len(redacted); i += 2 {
+		if key, ok := redacted[i-1].(string); ok && l.redactor.SensitiveKey(key) {
+			redacted[i] = utils.Redacted
+			continue
+		}
+		switch v := redacted[i].(type) {
+		case string:
+			redacted[i] = l.redactor.String(v)
+		case error:
+			redacted[i] = l.redactor.String(v.Error())
+		case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		default:
+			formatted := fmt.Sprintf("%+v", v)
+			if masked := l.redactor.String(formatted); masked != formatted {
+				redacted[i] = masked
+			}
+		}
+	}
+	return l.next.Log(redacted...)
+}
@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gotest.tools/assert"
+)
+
+func TestRedactLogger(t *testing.T) {
+	sensitive := []string{
+		"C-0042", "jane.doe@example.com", "+34 612 345 678", "(555) 123-4567",
+		"221 Baker Street", "Calle Mayor 5", "28013",
+	}
+	var buf bytes.Buffer
+	logger := log.With(NewRedactLogger(log.NewJSONLogger(&buf), utils.NewDefaultRedactor("postal")), "method", "Create")
+
+	t.Run("WHEN known sensitive values are logged SHOULD never reach the output", func(t *testing.T) {
+		buf.Reset()
+		level.Debug(logger).Log(
+			"customer_id", "C-0042",
+			"Email", "jane.doe@example.com",
+			"postal", "28013",
+			"msg", "calling +34 612 345 678 or (555) 123-4567 at 221 Baker Street",
+			"err", errors.New(`E11000 duplicate key { customer_id: "C-0042", address: "Calle Mayor 5" }`),
+		)
+		for _, value := range sensitive {
+			assert.Assert(t, !strings.Contains(buf.String(), value), "%s reached the log: %s", value, buf.String())
+		}
+		assert.Assert(t, strings.Contains(buf.String(), `"customer_id":"[REDACTED]"`))
+		assert.Assert(t, strings.Contains(buf.String(), `customer_id: [REDACTED]`))
+	})
+	t.Run("WHEN a struct with personal data is logged SHOULD mask it", func(t *testing.T) {
+		buf.Reset()
+		order := struct {
+			ID         string
+			CustomerID string
+			Contact    map[string]string
+		}{ID: "3f1c9a2e", CustomerID: "C-0042", Contact: map[string]string{"email": "jane.doe@example.com"}}
+		level.Debug(logger).Log("order", order, "orders", []interface{}{order}, "ids", []string{"3f1c9a2e"})
+		for _, value := range sensitive {
+			assert.Assert(t, !strings.Contains(buf.String(), value), "%s reached the log: %s", value, buf.String())
+		}
+		assert.Assert(t, strings.Contains(buf.String(), `"order":"{ID:3f1c9a2e CustomerID:[REDACTED] Contact:map[email:[REDACTED]]}"`), buf.String())
+		assert.Assert(t, strings.Contains(buf.String(), `"ids":["3f1c9a2e"]`), buf.String())
+	})
+	t.Run("WHEN numbers that are not phones are logged SHOULD keep them", func(t *testing.T) {
+		buf.Reset()
+		level.Info(logger).Log("id", "1234567890123", "ts", "1760870400000", "amount", "123456789.50",
+			"version", "v1234567890", "ip", "192.168.100.200", "count", "912345678")
+		assert.Equal(t, buf.String(), `{"amount":"123456789.50","count":"912345678","id":"1234567890123",`+
+			`"ip":"192.168.100.200","level":"info","method":"Create","ts":"1760870400000","version":"v1234567890"}`+"\n")
+	})
+	t.Run("WHEN phones of other shapes are logged SHOULD mask them", func(t *testing.T) {
+		for _, phone := range []string{"+14155552671", "+44 (20) 7946 0958", "555-123-4567", "612 34 56 78"} {
+			buf.Reset()
+			level.Info(logger).Log("msg", "call "+phone)
+			assert.Assert(t, strings.Contains(buf.String(), `"msg":"call [REDACTED]"`), "%s: %s", phone, buf.String())
+		}
+	})
+	t.Run("WHEN no personal data is logged SHOULD keep the values", func(t *testing.T) {
+		buf.Reset()
+		level.Info(logger).Log("id", "3f1c9a2e-7b1d-4c55-9e0a-1f2b3c4d5e6f", "restaurant_id", "EL MAGIO",
+			"count", 1234567890, "date", "2026-10-19")
+		assert.Equal(t, buf.String(), `{"count":1234567890,"date":"2026-10-19","id":"3f1c9a2e-7b1d-4c55-9e0a-1f2b3c4d5e6f",`+
+			`"level":"info","method":"Create","restaurant_id":"EL MAGIO"}`+"\n")
+	})
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+
+	"github.com/go-kit/kit/log"
+)
+
+type apiKeyRepositoryMem struct {
+	mu     sync.RWMutex
+	keys   map[string]model.APIKey
+	logger log.Logger
+}
+
+// NewAPIKeyRepositoryMem returns a concrete API key repository backed by a map
+func NewAPIKeyRepositoryMem(logger log.Logger) (domainRepo.IAPIKeyRepository, error) {
+	return &apiKeyRepositoryMem{
+		keys:   map[string]model.APIKey{},
+		logger: log.With(logger, "rep", "memory"),
+	}, nil
+}
+
+// Create stores a new key
+func (repo *apiKeyRepositoryMem) Create(ctx context.Context, key model.APIKey) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.keys[key.ID]; ok {
+		return domainRepo.ErrDuplicated
+	}
+	for _, stored := range repo.keys {
+		if stored.Hash == key.Hash {
+			return domainRepo.ErrDuplicated
+		}
+	}
+	repo.keys[key.ID] = key
+	return nil
+}
+
+// GetByHash returns the key with the hash
+func (repo *apiKeyRepositoryMem) GetByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for _, key := range repo.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return model.APIKey{}, domainRepo.ErrNotFound
+}
+
+// Revoke marks the key as revoked, keeping the first revocation time
+func (repo *apiKeyRepositoryMem) Revoke(ctx context.Context, id string, revokedAt int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key, ok := repo.keys[id]
+	if !ok {
+		return domainRepo.ErrNotFound
+	}
+	if !key.Revoked() {
+		key.RevokedAt = revokedAt
+		repo.keys[id] = key
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	apiKeyCollection = "api_keys"
+	hashField        = "hash"
+	revokedAtField   = "revoked_at"
+)
+
+type apiKeyRepositoryMongo struct {
+	collection *mongo.Collection
+	logger     log.Logger
+}
+
+// NewAPIKeyMongoRepository returns a concrete API key repository backed by mongo,
+// the keys are looked up by a unique index on their hash
+func NewAPIKeyMongoRepository(ctx context.Context, db *mongo.Database, logger log.Logger) (domainRepo.IAPIKeyRepository, error) {
+	collection := db.Collection(apiKeyCollection)
+	index := mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: hashField, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, err
+	}
+	return &apiKeyRepositoryMongo{
+		collection: collection,
+		logger:     log.With(logger, "rep", "mongo"),
+	}, nil
+}
+
+// Create stores a new key
+func (repo *apiKeyRepositoryMongo) Create(ctx context.Context, key model.APIKey) error {
+	if _, err := repo.collection.InsertOne(ctx, key); err != nil {
+		if isDuplicateKey(err) {
+			return domainRepo.ErrDuplicated
+		}
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return ErrMongoRepository
+	}
+	return nil
+}
+
+// GetByHash returns the key with the hash
+func (repo *apiKeyRepositoryMongo) GetByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	var key model.APIKey
+	filter := bson.D{bson.E{Key: hashField, Value: hash}}
+	if err := repo.collection.FindOne(ctx, filter).Decode(&key); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return model.APIKey{}, domainRepo.ErrNotFound
+		}
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return model.APIKey{}, ErrMongoRepository
+	}
+	return key, nil
+}
+
+// Revoke marks the key as revoked, keeping the first revocation time
+func (repo *apiKeyRepositoryMongo) Revoke(ctx context.Context, id string, revokedAt int64) error {
+	filter := bson.D{bson.E{Key: idField, Value: id}}
+	var key model.APIKey
+	if err := repo.collection.FindOne(ctx, filter).Decode(&key); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domainRepo.ErrNotFound
+		}
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return ErrMongoRepository
+	}
+	if key.Revoked() {
+		return nil
+	}
+	update := bson.D{bson.E{Key: "$set", Value: bson.D{bson.E{Key: revokedAtField, Value: revokedAt}}}}
+	if _, err := repo.collection.UpdateOne(ctx, filter, update); err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return ErrMongoRepository
+	}
+	return nil
+}
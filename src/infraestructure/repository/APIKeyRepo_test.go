@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+
+	"github.com/go-kit/kit/log"
+	"gotest.tools/assert"
+)
+
+func TestAPIKeyRepositoryMem(t *testing.T) {
+	ctx := context.TODO()
+	repo, err := NewAPIKeyRepositoryMem(log.NewNopLogger())
+	assert.NilError(t, err)
+	key := model.APIKey{ID: "k1", Name: "pos", Hash: "h1", Scopes: []string{model.ScopeOrdersRead}}
+	assert.NilError(t, repo.Create(ctx, key))
+
+	t.Run("WHEN the hash or the id are already stored SHOULD fail as duplicated", func(t *testing.T) {
+		assert.Equal(t, repo.Create(ctx, model.APIKey{ID: "k2", Hash: "h1"}), domainRepo.ErrDuplicated)
+		assert.Equal(t, repo.Create(ctx, model.APIKey{ID: "k1", Hash: "h2"}), domainRepo.ErrDuplicated)
+	})
+	t.Run("WHEN the hash is stored SHOULD return its key", func(t *testing.T) {
+		stored, err := repo.GetByHash(ctx, "h1")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, stored, key)
+		_, err = repo.GetByHash(ctx, "other")
+		assert.Equal(t, err, domainRepo.ErrNotFound)
+	})
+	t.Run("WHEN the key is revoked twice SHOULD keep the first time", func(t *testing.T) {
+		assert.NilError(t, repo.Revoke(ctx, "k1", 10))
+		assert.NilError(t, repo.Revoke(ctx, "k1", 20))
+		stored, _ := repo.GetByHash(ctx, "h1")
+		assert.Equal(t, stored.RevokedAt, int64(10))
+		assert.Equal(t, repo.Revoke(ctx, "other", 10), domainRepo.ErrNotFound)
+	})
+}
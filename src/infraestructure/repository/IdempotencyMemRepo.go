@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+
+	"github.com/go-kit/kit/log"
+)
+
+// idempotencySweepEvery is the number of reservations between the removals of the expired records
+const idempotencySweepEvery = 1000
+
+type idempotencyRepositoryMem struct {
+	mu       sync.Mutex
+	records  map[string]model.IdempotencyRecord
+	reserves int
+	logger   log.Logger
+}
+
+// NewIdempotencyRepositoryMem returns a concrete idempotency repository backed by a map,
+// the expired records are removed from time to time
+func NewIdempotencyRepositoryMem(logger log.Logger) (domainRepo.IIdempotencyRepository, error) {
+	return &idempotencyRepositoryMem{
+		records: map[string]model.IdempotencyRecord{},
+		logger:  log.With(logger, "rep", "memory"),
+	}, nil
+}
+
+// Reserve stores the record when its key is free or expired
+func (repo *idempotencyRepositoryMem) Reserve(ctx context.Context, record model.IdempotencyRecord) (model.IdempotencyRecord, bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+	repo.reserves++
+	if repo.reserves%idempotencySweepEvery == 0 {
+		repo.sweep(now)
+	}
+	if stored, ok := repo.records[record.Key]; ok && !stored.Expired(now) {
+		return stored, false, nil
+	}
+	repo.records[record.Key] = record
+	return record, true, nil
+}
+
+// Complete saves the response of a reserved key
+func (repo *idempotencyRepositoryMem) Complete(ctx context.Context, key string, response []byte) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.records[key]
+	if !ok {
+		return domainRepo.ErrNotFound
+	}
+	stored.Response = response
+	stored.Completed = true
+	repo.records[key] = stored
+	return nil
+}
+
+// Release frees a reserved key that has not been completed
+func (repo *idempotencyRepositoryMem) Release(ctx context.Context, key string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if stored, ok := repo.records[key]; ok && !stored.Completed {
+		delete(repo.records, key)
+	}
+	return nil
+}
+
+func (repo *idempotencyRepositoryMem) sweep(now time.Time) {
+	for key, stored := range repo.records {
+		if stored.Expired(now) {
+			delete(repo.records, key)
+		}
+	}
+}
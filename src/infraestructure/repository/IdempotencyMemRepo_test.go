@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"microservice_gokit_base/src/domain/model"
+
+	"github.com/go-kit/kit/log"
+	"gotest.tools/assert"
+)
+
+func TestIdempotencyRepositoryMem(t *testing.T) {
+	t.Run("WHEN the records expire SHOULD remove them on a later sweep", func(t *testing.T) {
+		rep, err := NewIdempotencyRepositoryMem(log.NewNopLogger())
+		assert.NilError(t, err)
+		expired := time.Now().Add(-time.Minute)
+		for i := 0; i < idempotencySweepEvery-1; i++ {
+			_, reserved, err := rep.Reserve(context.TODO(), model.IdempotencyRecord{Key: strconv.Itoa(i), ExpiresAt: expired})
+			assert.NilError(t, err)
+			assert.Assert(t, reserved)
+		}
+		live := model.IdempotencyRecord{Key: "live", ExpiresAt: time.Now().Add(time.Hour)}
+		_, _, err = rep.Reserve(context.TODO(), live)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, rep.(*idempotencyRepositoryMem).records, map[string]model.IdempotencyRecord{"live": live})
+	})
+}
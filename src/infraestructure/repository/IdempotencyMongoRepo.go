@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/domain/utils"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	idempotencyCollection = "idempotency"
+	expiresAtField        = "expires_at"
+	completedField        = "completed"
+	responseField         = "response"
+	duplicateKeyCode      = 11000
+)
+
+type idempotencyRepositoryMongo struct {
+	collection *mongo.Collection
+	logger     log.Logger
+}
+
+// NewIdempotencyMongoRepository returns a concrete idempotency repository backed by mongo,
+// expired keys are removed by a TTL index on the collection
+func NewIdempotencyMongoRepository(ctx context.Context, db *mongo.Database, logger log.Logger) (domainRepo.IIdempotencyRepository, error) {
+	collection := db.Collection(idempotencyCollection)
+	index := mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: expiresAtField, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return nil, err
+	}
+	return &idempotencyRepositoryMongo{
+		collection: collection,
+		logger:     log.With(logger, "rep", "mongo"),
+	}, nil
+}
+
+// Reserve stores the record when its key is free or expired
+func (repo *idempotencyRepositoryMongo) Reserve(ctx context.Context, record model.IdempotencyRecord) (model.IdempotencyRecord, bool, error) {
+	_, err := repo.collection.InsertOne(ctx, record)
+	if err == nil {
+		return record, true, nil
+	}
+	if !isDuplicateKey(err) {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return record, false, ErrMongoRepository
+	}
+
+	var stored model.IdempotencyRecord
+	filter := bson.D{bson.E{Key: idField, Value: record.Key}}
+	if err := repo.collection.FindOne(ctx, filter).Decode(&stored); err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return record, false, ErrMongoRepository
+	}
+	if !stored.Expired(time.Now()) {
+		return stored, false, nil
+	}
+
+	// the TTL monitor has not removed the expired key yet, take it over
+	filter = append(filter, bson.E{Key: expiresAtField, Value: stored.ExpiresAt})
+	replaceResult, err := repo.collection.ReplaceOne(ctx, filter, record)
+	if err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return record, false, ErrMongoRepository
+	}
+	if replaceResult.ModifiedCount == 0 {
+		return repo.Reserve(ctx, record)
+	}
+	return record, true, nil
+}
+
+// Complete saves the response of a reserved key
+func (repo *idempotencyRepositoryMongo) Complete(ctx context.Context, key string, response []byte) error {
+	filter := bson.D{bson.E{Key: idField, Value: key}}
+	update := bson.D{
+		bson.E{Key: "$set", Value: bson.D{
+			bson.E{Key: responseField, Value: response},
+			bson.E{Key: completedField, Value: true},
+		}},
+	}
+	updateResult, err := repo.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return ErrMongoRepository
+	}
+	if updateResult.MatchedCount == 0 {
+		return domainRepo.ErrNotFound
+	}
+	return nil
+}
+
+// Release frees a reserved key that has not been completed
+func (repo *idempotencyRepositoryMongo) Release(ctx context.Context, key string) error {
+	filter := bson.D{
+		bson.E{Key: idField, Value: key},
+		bson.E{Key: completedField, Value: false},
+	}
+	if _, err := repo.collection.DeleteOne(ctx, filter); err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return ErrMongoRepository
+	}
+	return nil
+}
+
+func isDuplicateKey(err error) bool {
+	if writeErr, ok := err.(mongo.WriteException); ok {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	return false
+}
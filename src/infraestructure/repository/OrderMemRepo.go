@@ -3,7 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
-	"strconv"
+	"sync"
 
 	"microservice_gokit_base/src/domain/model"
 	domainRepo "microservice_gokit_base/src/domain/repository"
@@ -17,6 +17,7 @@ var (
 
 type DbMemory struct {
 	Data []model.Order
+	mu   sync.RWMutex
 }
 
 type repositoryMem struct {
@@ -35,52 +36,212 @@ func NewOrderRepositoryMem(db *DbMemory, logger log.Logger) (domainRepo.IOrderRe
 
 // CreateOrder inserts a new order and its order items into db
 func (repo *repositoryMem) CreateOrder(ctx context.Context, order model.Order) (string, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
 
 	repo.db.Data = append(repo.db.Data, order)
-	return strconv.Itoa(len(repo.db.Data)), nil
+	return order.ID, nil
+}
+
+// CreateOrders inserts the orders under a single lock
+func (repo *repositoryMem) CreateOrders(ctx context.Context, orders []model.Order) ([]error, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+
+	ids := make(map[string]bool, len(repo.db.Data)+len(orders))
+	for i := range repo.db.Data {
+		ids[repo.db.Data[i].ID] = true
+	}
+	errs := make([]error, len(orders))
+	for i, order := range orders {
+		if ids[order.ID] {
+			errs[i] = domainRepo.ErrDuplicated
+			continue
+		}
+		ids[order.ID] = true
+		repo.db.Data = append(repo.db.Data, order)
+	}
+	return errs, nil
 }
 
 // ChangeOrderStatus changes the order status
-func (repo *repositoryMem) ChangeOrderStatus(ctx context.Context, id string, status string) (int64, error) {
-	for i := 0; i <= len(repo.db.Data); i++ {
+func (repo *repositoryMem) ChangeOrderStatus(ctx context.Context, id string, change model.StatusChange,
+	cond domainRepo.UpdateCondition) (int64, error) {
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+
+	for i := range repo.db.Data {
 		if repo.db.Data[i].ID == id {
-			repo.db.Data[i].Status = status
+			if cond.Status != "" && repo.db.Data[i].Status != cond.Status {
+				return 0, domainRepo.StatusConflictError{Expected: cond.Status, Actual: repo.db.Data[i].Status}
+			}
+			if cond.CheckVersion && repo.db.Data[i].Version != cond.Version {
+				return 0, domainRepo.ErrVersionConflict
+			}
+			repo.db.Data[i].ApplyStatusChange(change)
+			repo.db.Data[i].Version++
 			return 1, nil
 		}
 	}
-	return 0, nil
+	return 0, domainRepo.ErrNotFound
+}
+
+// ChangeOrdersStatus changes the status of the given orders meeting the condition under a single lock
+func (repo *repositoryMem) ChangeOrdersStatus(ctx context.Context, ids []string, change model.StatusChange,
+	cond domainRepo.UpdateCondition) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	repo.db.mu.Lock()
+	defer repo.db.mu.Unlock()
+
+	var changed int64
+	filter := domainRepo.OrderFilter{IDs: ids, Status: cond.Status}
+	for i := range repo.db.Data {
+		if !matches(repo.db.Data[i], filter) {
+			continue
+		}
+		if cond.CheckVersion && repo.db.Data[i].Version != cond.Version {
+			continue
+		}
+		repo.db.Data[i].ApplyStatusChange(change)
+		repo.db.Data[i].Version++
+		changed++
+	}
+	return changed, nil
 }
 
 // GetOrderByID query the order by given id
 func (repo *repositoryMem) GetOrderByID(ctx context.Context, id string) (model.Order, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+
 	var orderRow = model.Order{}
-	for i := 0; i <= len(repo.db.Data); i++ {
+	for i := range repo.db.Data {
 		if repo.db.Data[i].ID == id {
 			return repo.db.Data[i], nil
 		}
 	}
-	return orderRow, nil
+	return orderRow, domainRepo.ErrNotFound
 }
 
 // GetAll query all orders
-func (repo *repositoryMem) GetAll(ctx context.Context) ([]*model.Order, error) {
+func (repo *repositoryMem) GetAll(ctx context.Context, filter domainRepo.OrderFilter) ([]*model.Order, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+
 	var results []*model.Order
 	for _, elem := range repo.db.Data {
-		results = append(results, &elem)
+		if matches(elem, filter) {
+			order := elem
+			results = append(results, &order)
+		}
 	}
 	return results, nil
 }
 
 // GetPage query orders by a page
-func (repo *repositoryMem) GetPage(ctx context.Context, page int64, size int64) ([]*model.Order, error) {
+func (repo *repositoryMem) GetPage(ctx context.Context, filter domainRepo.OrderFilter, page int64, size int64) ([]*model.Order, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+
 	var results []*model.Order
+	skip := page * size
 	for _, elem := range repo.db.Data {
-		results = append(results, &elem)
+		if int64(len(results)) == size {
+			break
+		}
+		if !matches(elem, filter) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		order := elem
+		results = append(results, &order)
 	}
 	return results, nil
 }
 
+// Iterate returns an iterator over a snapshot of the orders of the filter
+func (repo *repositoryMem) Iterate(ctx context.Context, filter domainRepo.OrderFilter) (domainRepo.IOrderIterator, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+
+	var orders []model.Order
+	for _, elem := range repo.db.Data {
+		if matches(elem, filter) {
+			orders = append(orders, elem)
+		}
+	}
+	return &sliceIterator{orders: orders, pos: -1}, nil
+}
+
 // Count get the count of documents
 func (repo *repositoryMem) Count(ctx context.Context) (int64, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+
 	return int64(len(repo.db.Data)), nil
 }
+
+// CountFilter get the count of the orders of the filter
+func (repo *repositoryMem) CountFilter(ctx context.Context, filter domainRepo.OrderFilter) (int64, error) {
+	repo.db.mu.RLock()
+	defer repo.db.mu.RUnlock()
+
+	var counted int64
+	for _, order := range repo.db.Data {
+		if matches(order, filter) {
+			counted++
+		}
+	}
+	return counted, nil
+}
+
+// matches tells if an order meets the filter
+func matches(order model.Order, filter domainRepo.OrderFilter) bool {
+	if filter.RestaurantID != "" && order.RestaurantID != filter.RestaurantID {
+		return false
+	}
+	if filter.CustomerID != "" && order.CustomerID != filter.CustomerID {
+		return false
+	}
+	if filter.Status != "" && order.Status != filter.Status {
+		return false
+	}
+	if len(filter.IDs) == 0 {
+		return true
+	}
+	for _, id := range filter.IDs {
+		if order.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+type sliceIterator struct {
+	orders []model.Order
+	pos    int
+	err    error
+}
+
+func (it *sliceIterator) Next(ctx context.Context) bool {
+	if it.err = ctx.Err(); it.err != nil || it.pos >= len(it.orders)-1 {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *sliceIterator) Decode(order *model.Order) error {
+	*order = it.orders[it.pos]
+	return nil
+}
+
+// Err returns the error of the context when it ended the iteration
+func (it *sliceIterator) Err() error { return it.err }
+
+func (it *sliceIterator) Close(ctx context.Context) error { return nil }
@@ -12,6 +12,7 @@ import (
 	"microservice_gokit_base/config"
 	"microservice_gokit_base/src/domain/model"
 	domainRepo "microservice_gokit_base/src/domain/repository"
+	"microservice_gokit_base/src/domain/utils"
 
 	"github.com/go-kit/kit/log"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -24,13 +25,18 @@ var (
 	// ErrConnectionMongoRepository when the conection fails
 	ErrConnectionMongoRepository = errors.New("error connecting on the mongo database")
 	// ErrNotFoundMongoRepository when the query returns no results
-	ErrNotFoundMongoRepository = errors.New("error no results found")
+	ErrNotFoundMongoRepository = domainRepo.ErrNotFound
 )
 
 const (
-	collection  = "order"
-	idField     = "_id"
-	statusField = "status"
+	collection         = "order"
+	idField            = "_id"
+	statusField        = "status"
+	updatedOnField     = "updated_on"
+	statusHistoryField = "status_history"
+	versionField       = "version"
+	restaurantIDField  = "restaurant_id"
+	customerIDField    = "customer_id"
 )
 
 type repositoryMongo struct {
@@ -69,30 +75,148 @@ func (repo *repositoryMongo) CreateOrder(ctx context.Context, order model.Order)
 
 	insertResult, err := repo.collection.InsertOne(ctx, order)
 	if err != nil {
-		level.Error(repo.logger).Log("err", err)
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		if isDuplicateKey(err) {
+			return "", domainRepo.ErrDuplicated
+		}
+		return "", ErrMongoRepository
+	}
+	if id, ok := insertResult.InsertedID.(string); ok {
+		return id, nil
 	}
-	return insertResult.InsertedID.(string), nil
+	return order.ID, nil
+}
+
+// CreateOrders inserts the orders unordered so a failing order does not stop the rest
+func (repo *repositoryMongo) CreateOrders(ctx context.Context, orders []model.Order) ([]error, error) {
+	errs := make([]error, len(orders))
+	documents := make([]interface{}, len(orders))
+	for i := range orders {
+		documents[i] = orders[i]
+	}
+	insertOptions := options.InsertMany().SetOrdered(false)
+	_, err := repo.collection.InsertMany(ctx, documents, insertOptions)
+	if err == nil {
+		return errs, nil
+	}
+	bulkErr, ok := err.(mongo.BulkWriteException)
+	if !ok || bulkErr.WriteConcernError != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return nil, ErrMongoRepository
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code == duplicateKeyCode {
+			errs[writeErr.Index] = domainRepo.ErrDuplicated
+			continue
+		}
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", writeErr)
+		errs[writeErr.Index] = ErrMongoRepository
+	}
+	return errs, nil
 }
 
 // ChangeOrderStatus changes the order status
-func (repo *repositoryMongo) ChangeOrderStatus(ctx context.Context, orderID string, status string) (int64, error) {
+func (repo *repositoryMongo) ChangeOrderStatus(ctx context.Context, orderID string, change model.StatusChange,
+	cond domainRepo.UpdateCondition) (int64, error) {
 	filter := bson.D{bson.E{Key: idField, Value: orderID}}
-	update := bson.D{
-		{"$set", bson.D{
-			{statusField, status},
-		}},
-		{"$currentDate", bson.D{
-			{"lastModified", true},
-		}},
+	if cond.CheckVersion {
+		filter = append(filter, versionFilter(cond.Version))
 	}
-	updateResult, err := repo.collection.UpdateOne(ctx, filter, update)
+	if cond.Status != "" {
+		filter = append(filter, bson.E{Key: statusField, Value: cond.Status})
+	}
+	updateResult, err := repo.collection.UpdateOne(ctx, filter, statusUpdate(change))
 	if err != nil {
-		level.Error(repo.logger).Log("err", err)
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
 		return 0, err
 	}
+	if updateResult.MatchedCount == 0 {
+		return 0, repo.conflictOn(ctx, orderID, cond)
+	}
+	return updateResult.ModifiedCount, nil
+}
+
+// ChangeOrdersStatus changes the status of the given orders meeting the condition with a single update
+func (repo *repositoryMongo) ChangeOrdersStatus(ctx context.Context, ids []string, change model.StatusChange,
+	cond domainRepo.UpdateCondition) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	filter := toBsonFilter(domainRepo.OrderFilter{IDs: ids, Status: cond.Status})
+	if cond.CheckVersion {
+		filter = append(filter, versionFilter(cond.Version))
+	}
+	updateResult, err := repo.collection.UpdateMany(ctx, filter, statusUpdate(change))
+	if err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return 0, ErrMongoRepository
+	}
 	return updateResult.ModifiedCount, nil
 }
 
+// statusUpdate returns the update that records a status change on an order
+func statusUpdate(change model.StatusChange) bson.D {
+	set := bson.D{
+		bson.E{Key: statusField, Value: change.Status},
+		bson.E{Key: updatedOnField, Value: change.Timestamp},
+	}
+	if field, ok := model.StatusTimestampField(change.Status); ok {
+		set = append(set, bson.E{Key: field, Value: change.Timestamp})
+	}
+	return bson.D{
+		bson.E{Key: "$set", Value: set},
+		bson.E{Key: "$push", Value: bson.D{
+			bson.E{Key: statusHistoryField, Value: change},
+		}},
+		bson.E{Key: "$inc", Value: bson.D{
+			bson.E{Key: versionField, Value: 1},
+		}},
+	}
+}
+
+// versionFilter matches the orders at the version, version zero matching the orders stored without one
+func versionFilter(version int64) bson.E {
+	if version == 0 {
+		return bson.E{Key: versionField, Value: bson.D{bson.E{Key: "$in", Value: bson.A{0, nil}}}}
+	}
+	return bson.E{Key: versionField, Value: version}
+}
+
+// toBsonFilter returns the query of an order filter
+func toBsonFilter(filter domainRepo.OrderFilter) bson.D {
+	query := bson.D{}
+	if len(filter.IDs) > 0 {
+		query = append(query, bson.E{Key: idField, Value: bson.D{
+			bson.E{Key: "$in", Value: filter.IDs},
+		}})
+	}
+	if filter.RestaurantID != "" {
+		query = append(query, bson.E{Key: restaurantIDField, Value: filter.RestaurantID})
+	}
+	if filter.CustomerID != "" {
+		query = append(query, bson.E{Key: customerIDField, Value: filter.CustomerID})
+	}
+	if filter.Status != "" {
+		query = append(query, bson.E{Key: statusField, Value: filter.Status})
+	}
+	return query
+}
+
+// conflictOn tells why a write on an order matched nothing, ErrNotFound when the order does not exist
+func (repo *repositoryMongo) conflictOn(ctx context.Context, orderID string, cond domainRepo.UpdateCondition) error {
+	current, err := repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if cond.Status != "" && current.Status != cond.Status {
+		return domainRepo.StatusConflictError{Expected: cond.Status, Actual: current.Status}
+	}
+	if cond.CheckVersion {
+		return domainRepo.ErrVersionConflict
+	}
+	return domainRepo.ErrNotFound
+}
+
 // GetOrderByID query the order by given id
 func (repo *repositoryMongo) GetOrderByID(ctx context.Context, id string) (model.Order, error) {
 	filter := bson.D{bson.E{Key: idField, Value: id}}
@@ -101,20 +225,20 @@ func (repo *repositoryMongo) GetOrderByID(ctx context.Context, id string) (model
 	var result model.Order
 	err := findResult.Decode(&result)
 	if err != nil {
-		level.Debug(repo.logger).Log("msg", err)
+		level.Debug(utils.LoggerFromContext(ctx, repo.logger)).Log("msg", err)
 		return result, ErrNotFoundMongoRepository
 	}
 	return result, err
 }
 
 // GetAll query all orders
-func (repo *repositoryMongo) GetAll(ctx context.Context) ([]*model.Order, error) {
+func (repo *repositoryMongo) GetAll(ctx context.Context, filter domainRepo.OrderFilter) ([]*model.Order, error) {
 
 	var results []*model.Order
 	findOptions := options.Find()
-	cur, err := repo.collection.Find(ctx, bson.D{}, findOptions)
+	cur, err := repo.collection.Find(ctx, toBsonFilter(filter), findOptions)
 	if err != nil {
-		level.Error(repo.logger).Log("err", err)
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
 		return nil, ErrMongoRepository
 	}
 	defer cur.Close(ctx)
@@ -122,27 +246,27 @@ func (repo *repositoryMongo) GetAll(ctx context.Context) ([]*model.Order, error)
 		var result model.Order
 		err := cur.Decode(&result)
 		if err != nil {
-			level.Debug(repo.logger).Log("msg", err)
+			level.Debug(utils.LoggerFromContext(ctx, repo.logger)).Log("msg", err)
 		}
 		results = append(results, &result)
 	}
 	if err := cur.Err(); err != nil {
-		level.Debug(repo.logger).Log("msg", err)
+		level.Debug(utils.LoggerFromContext(ctx, repo.logger)).Log("msg", err)
 		return nil, ErrNotFoundMongoRepository
 	}
 	return results, nil
 }
 
 // GetPage query orders by a page
-func (repo *repositoryMongo) GetPage(ctx context.Context, page int64, size int64) ([]*model.Order, error) {
+func (repo *repositoryMongo) GetPage(ctx context.Context, filter domainRepo.OrderFilter, page int64, size int64) ([]*model.Order, error) {
 
 	var results []*model.Order
 	findOptions := options.Find()
 	findOptions.SetLimit(size)
 	findOptions.SetSkip(page * size)
-	cur, err := repo.collection.Find(ctx, bson.D{}, findOptions)
+	cur, err := repo.collection.Find(ctx, toBsonFilter(filter), findOptions)
 	if err != nil {
-		level.Error(repo.logger).Log("err", err)
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
 		return nil, ErrMongoRepository
 	}
 	defer cur.Close(ctx)
@@ -150,24 +274,56 @@ func (repo *repositoryMongo) GetPage(ctx context.Context, page int64, size int64
 		var result model.Order
 		err := cur.Decode(&result)
 		if err != nil {
-			level.Debug(repo.logger).Log("msg", err)
+			level.Debug(utils.LoggerFromContext(ctx, repo.logger)).Log("msg", err)
 		}
 		results = append(results, &result)
 	}
 	if err := cur.Err(); err != nil {
-		level.Debug(repo.logger).Log("msg", err)
+		level.Debug(utils.LoggerFromContext(ctx, repo.logger)).Log("msg", err)
 		return nil, ErrNotFoundMongoRepository
 	}
 	return results, nil
 }
 
+// Iterate returns an iterator over the cursor of the orders of the filter
+func (repo *repositoryMongo) Iterate(ctx context.Context, filter domainRepo.OrderFilter) (domainRepo.IOrderIterator, error) {
+	cur, err := repo.collection.Find(ctx, toBsonFilter(filter), options.Find())
+	if err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return nil, ErrMongoRepository
+	}
+	return &cursorIterator{cursor: cur}, nil
+}
+
 // Count get the count of documents
 func (repo *repositoryMongo) Count(ctx context.Context) (int64, error) {
 	countOptions := options.Count()
 	counted, err := repo.collection.CountDocuments(ctx, bson.D{}, countOptions)
 	if err != nil {
-		level.Error(repo.logger).Log("err", err)
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
 		return -1, ErrMongoRepository
 	}
 	return counted, nil
 }
+
+// CountFilter get the count of the documents of the filter
+func (repo *repositoryMongo) CountFilter(ctx context.Context, filter domainRepo.OrderFilter) (int64, error) {
+	counted, err := repo.collection.CountDocuments(ctx, toBsonFilter(filter), options.Count())
+	if err != nil {
+		level.Error(utils.LoggerFromContext(ctx, repo.logger)).Log("err", err)
+		return -1, ErrMongoRepository
+	}
+	return counted, nil
+}
+
+type cursorIterator struct {
+	cursor *mongo.Cursor
+}
+
+func (it *cursorIterator) Next(ctx context.Context) bool { return it.cursor.Next(ctx) }
+
+func (it *cursorIterator) Decode(order *model.Order) error { return it.cursor.Decode(order) }
+
+func (it *cursorIterator) Err() error { return it.cursor.Err() }
+
+func (it *cursorIterator) Close(ctx context.Context) error { return it.cursor.Close(ctx) }
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
+
 	"microservice_gokit_base/src/domain/model"
+	domainRepo "microservice_gokit_base/src/domain/repository"
 
 	"os"
 	"testing"
@@ -24,6 +27,8 @@ func TestOrderRepository(t *testing.T) {
 		)
 	}
 
+	ctx := context.TODO()
+
 	// Create DB
 	var dbMemory = &DbMemory{
 		Data: []model.Order{},
@@ -35,4 +40,174 @@ func TestOrderRepository(t *testing.T) {
 			assert.NilError(t, err)
 			assert.Assert(t, repo != nil)
 		})
+
+	t.Run("repositoryMem.ChangeOrderStatus",
+		func(t *testing.T) {
+			repo, _ := NewOrderRepositoryMem(dbMemory, logger)
+			_, err := repo.CreateOrder(ctx, model.Order{ID: "1", Status: model.StatusPending, Version: 1})
+			assert.NilError(t, err)
+
+			t.Run("WHEN the order exists SHOULD record the change on the history and timestamps",
+				func(t *testing.T) {
+					change := model.StatusChange{
+						Status:    model.StatusAccepted,
+						Timestamp: 100,
+						Actor:     "kitchen",
+					}
+					changed, err := repo.ChangeOrderStatus(ctx, "1", change, domainRepo.UpdateCondition{})
+					assert.NilError(t, err)
+					assert.Assert(t, changed == 1)
+
+					order, err := repo.GetOrderByID(ctx, "1")
+					assert.NilError(t, err)
+					assert.Equal(t, order.Version, int64(2))
+					assert.Equal(t, order.Status, model.StatusAccepted)
+					assert.Equal(t, order.AcceptedOn, int64(100))
+					assert.Equal(t, order.UpdatedOn, int64(100))
+					assert.DeepEqual(t, order.StatusHistory, []model.StatusChange{change})
+				})
+
+			t.Run("WHEN the expected version is outdated SHOULD return a conflict",
+				func(t *testing.T) {
+					change := model.StatusChange{Status: model.StatusPreparing}
+					changed, err := repo.ChangeOrderStatus(ctx, "1", change, domainRepo.UpdateCondition{Version: 1, CheckVersion: true})
+					assert.Equal(t, err, domainRepo.ErrVersionConflict)
+					assert.Assert(t, changed == 0)
+
+					changed, err = repo.ChangeOrderStatus(ctx, "1", change, domainRepo.UpdateCondition{Version: 2, CheckVersion: true})
+					assert.NilError(t, err)
+					assert.Assert(t, changed == 1)
+				})
+
+			t.Run("WHEN the order was stored without a version SHOULD match the version zero",
+				func(t *testing.T) {
+					_, err := repo.CreateOrder(ctx, model.Order{ID: "legacy", Status: model.StatusPending})
+					assert.NilError(t, err)
+					change := model.StatusChange{Status: model.StatusAccepted}
+					changed, err := repo.ChangeOrderStatus(ctx, "legacy", change, domainRepo.UpdateCondition{CheckVersion: true})
+					assert.NilError(t, err)
+					assert.Assert(t, changed == 1)
+				})
+
+			t.Run("WHEN the order is not in the expected status SHOULD return the actual status",
+				func(t *testing.T) {
+					change := model.StatusChange{Status: model.StatusDelivered}
+					cond := domainRepo.UpdateCondition{Status: model.StatusAccepted}
+					changed, err := repo.ChangeOrderStatus(ctx, "1", change, cond)
+					assert.DeepEqual(t, err, domainRepo.StatusConflictError{
+						Expected: model.StatusAccepted,
+						Actual:   model.StatusPreparing,
+					})
+					assert.Assert(t, changed == 0)
+
+					cond.Status = model.StatusPreparing
+					changed, err = repo.ChangeOrderStatus(ctx, "1", change, cond)
+					assert.NilError(t, err)
+					assert.Assert(t, changed == 1)
+				})
+
+			t.Run("WHEN the order does not exist SHOULD fail as not found",
+				func(t *testing.T) {
+					changed, err := repo.ChangeOrderStatus(ctx, "404", model.StatusChange{Status: model.StatusAccepted},
+						domainRepo.UpdateCondition{})
+					assert.Equal(t, err, domainRepo.ErrNotFound)
+					assert.Assert(t, changed == 0)
+					_, err = repo.ChangeOrderStatus(ctx, "404", model.StatusChange{Status: model.StatusAccepted},
+						domainRepo.UpdateCondition{Version: 1, CheckVersion: true})
+					assert.Equal(t, err, domainRepo.ErrNotFound)
+
+					_, err = repo.GetOrderByID(ctx, "404")
+					assert.Equal(t, err, domainRepo.ErrNotFound)
+				})
+		})
+
+	t.Run("repositoryMem.CreateOrders",
+		func(t *testing.T) {
+			repo, _ := NewOrderRepositoryMem(&DbMemory{}, logger)
+
+			t.Run("WHEN an id already exists SHOULD insert the rest of orders",
+				func(t *testing.T) {
+					_, err := repo.CreateOrder(ctx, model.Order{ID: "1"})
+					assert.NilError(t, err)
+
+					errs, err := repo.CreateOrders(ctx, []model.Order{{ID: "1"}, {ID: "2"}, {ID: "2"}})
+					assert.NilError(t, err)
+					assert.Assert(t, len(errs) == 3)
+					assert.Equal(t, errs[0], domainRepo.ErrDuplicated)
+					assert.NilError(t, errs[1])
+					assert.Equal(t, errs[2], domainRepo.ErrDuplicated)
+
+					counted, err := repo.Count(ctx)
+					assert.NilError(t, err)
+					assert.Equal(t, counted, int64(2))
+				})
+		})
+
+	t.Run("repositoryMem.ChangeOrdersStatus",
+		func(t *testing.T) {
+			repo, _ := NewOrderRepositoryMem(&DbMemory{}, logger)
+			_, err := repo.CreateOrders(ctx, []model.Order{
+				{ID: "1", RestaurantID: "A", Status: model.StatusPending},
+				{ID: "2", RestaurantID: "A", Status: model.StatusAccepted},
+				{ID: "3", RestaurantID: "B", Status: model.StatusPending},
+			})
+			assert.NilError(t, err)
+
+			t.Run("WHEN some orders are not in the expected status SHOULD change only the others",
+				func(t *testing.T) {
+					change := model.StatusChange{Status: model.StatusRejected, Timestamp: 100}
+					cond := domainRepo.UpdateCondition{Status: model.StatusPending}
+					changed, err := repo.ChangeOrdersStatus(ctx, []string{"1", "2"}, change, cond)
+					assert.NilError(t, err)
+					assert.Equal(t, changed, int64(1))
+
+					rejected, err := repo.GetAll(ctx, domainRepo.OrderFilter{Status: model.StatusRejected})
+					assert.NilError(t, err)
+					assert.Assert(t, len(rejected) == 1)
+					assert.Equal(t, rejected[0].ID, "1")
+					assert.Equal(t, rejected[0].RejectedOn, int64(100))
+				})
+
+			t.Run("WHEN no ids are given SHOULD change nothing",
+				func(t *testing.T) {
+					changed, err := repo.ChangeOrdersStatus(ctx, nil, model.StatusChange{Status: model.StatusRejected},
+						domainRepo.UpdateCondition{})
+					assert.NilError(t, err)
+					assert.Equal(t, changed, int64(0))
+				})
+		})
+
+	t.Run("repositoryMem.CountFilter",
+		func(t *testing.T) {
+			repo, _ := NewOrderRepositoryMem(&DbMemory{}, logger)
+			_, err := repo.CreateOrders(ctx, []model.Order{
+				{ID: "1", RestaurantID: "A"}, {ID: "2", RestaurantID: "A"}, {ID: "3", RestaurantID: "B"},
+			})
+			assert.NilError(t, err)
+
+			t.Run("WHEN the filter is given SHOULD count only its orders",
+				func(t *testing.T) {
+					counted, err := repo.CountFilter(ctx, domainRepo.OrderFilter{RestaurantID: "A"})
+					assert.NilError(t, err)
+					assert.Equal(t, counted, int64(2))
+				})
+		})
+
+	t.Run("repositoryMem.Iterate",
+		func(t *testing.T) {
+			repo, _ := NewOrderRepositoryMem(&DbMemory{}, logger)
+			_, err := repo.CreateOrders(ctx, []model.Order{{ID: "1"}, {ID: "2"}})
+			assert.NilError(t, err)
+
+			t.Run("WHEN the context ends SHOULD stop with its error",
+				func(t *testing.T) {
+					it, err := repo.Iterate(ctx, domainRepo.OrderFilter{})
+					assert.NilError(t, err)
+					cancelled, cancel := context.WithCancel(ctx)
+					assert.Assert(t, it.Next(cancelled))
+					cancel()
+					assert.Assert(t, !it.Next(cancelled))
+					assert.Equal(t, it.Err(), context.Canceled)
+				})
+		})
 }
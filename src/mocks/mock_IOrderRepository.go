@@ -8,6 +8,7 @@ import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
 	model "microservice_gokit_base/src/domain/model"
+	repository "microservice_gokit_base/src/domain/repository"
 	reflect "reflect"
 )
 
@@ -35,18 +36,33 @@ func (m *MockIOrderRepository) EXPECT() *MockIOrderRepositoryMockRecorder {
 }
 
 // ChangeOrderStatus mocks base method
-func (m *MockIOrderRepository) ChangeOrderStatus(arg0 context.Context, arg1, arg2 string) (int64, error) {
+func (m *MockIOrderRepository) ChangeOrderStatus(arg0 context.Context, arg1 string, arg2 model.StatusChange, arg3 repository.UpdateCondition) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChangeOrderStatus", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "ChangeOrderStatus", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ChangeOrderStatus indicates an expected call of ChangeOrderStatus
-func (mr *MockIOrderRepositoryMockRecorder) ChangeOrderStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockIOrderRepositoryMockRecorder) ChangeOrderStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeOrderStatus", reflect.TypeOf((*MockIOrderRepository)(nil).ChangeOrderStatus), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeOrderStatus", reflect.TypeOf((*MockIOrderRepository)(nil).ChangeOrderStatus), arg0, arg1, arg2, arg3)
+}
+
+// ChangeOrdersStatus mocks base method
+func (m *MockIOrderRepository) ChangeOrdersStatus(arg0 context.Context, arg1 []string, arg2 model.StatusChange, arg3 repository.UpdateCondition) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeOrdersStatus", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeOrdersStatus indicates an expected call of ChangeOrdersStatus
+func (mr *MockIOrderRepositoryMockRecorder) ChangeOrdersStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeOrdersStatus", reflect.TypeOf((*MockIOrderRepository)(nil).ChangeOrdersStatus), arg0, arg1, arg2, arg3)
 }
 
 // Count mocks base method
@@ -64,6 +80,21 @@ func (mr *MockIOrderRepositoryMockRecorder) Count(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockIOrderRepository)(nil).Count), arg0)
 }
 
+// CountFilter mocks base method
+func (m *MockIOrderRepository) CountFilter(arg0 context.Context, arg1 repository.OrderFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFilter", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFilter indicates an expected call of CountFilter
+func (mr *MockIOrderRepositoryMockRecorder) CountFilter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFilter", reflect.TypeOf((*MockIOrderRepository)(nil).CountFilter), arg0, arg1)
+}
+
 // CreateOrder mocks base method
 func (m *MockIOrderRepository) CreateOrder(arg0 context.Context, arg1 model.Order) (string, error) {
 	m.ctrl.T.Helper()
@@ -79,19 +110,34 @@ func (mr *MockIOrderRepositoryMockRecorder) CreateOrder(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockIOrderRepository)(nil).CreateOrder), arg0, arg1)
 }
 
+// CreateOrders mocks base method
+func (m *MockIOrderRepository) CreateOrders(arg0 context.Context, arg1 []model.Order) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrders", arg0, arg1)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrders indicates an expected call of CreateOrders
+func (mr *MockIOrderRepositoryMockRecorder) CreateOrders(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrders", reflect.TypeOf((*MockIOrderRepository)(nil).CreateOrders), arg0, arg1)
+}
+
 // GetAll mocks base method
-func (m *MockIOrderRepository) GetAll(arg0 context.Context) ([]*model.Order, error) {
+func (m *MockIOrderRepository) GetAll(arg0 context.Context, arg1 repository.OrderFilter) ([]*model.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", arg0)
+	ret := m.ctrl.Call(m, "GetAll", arg0, arg1)
 	ret0, _ := ret[0].([]*model.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll
-func (mr *MockIOrderRepositoryMockRecorder) GetAll(arg0 interface{}) *gomock.Call {
+func (mr *MockIOrderRepositoryMockRecorder) GetAll(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockIOrderRepository)(nil).GetAll), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockIOrderRepository)(nil).GetAll), arg0, arg1)
 }
 
 // GetOrderByID mocks base method
@@ -110,16 +156,31 @@ func (mr *MockIOrderRepositoryMockRecorder) GetOrderByID(arg0, arg1 interface{})
 }
 
 // GetPage mocks base method
-func (m *MockIOrderRepository) GetPage(arg0 context.Context, arg1, arg2 int64) ([]*model.Order, error) {
+func (m *MockIOrderRepository) GetPage(arg0 context.Context, arg1 repository.OrderFilter, arg2, arg3 int64) ([]*model.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPage", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetPage", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]*model.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPage indicates an expected call of GetPage
-func (mr *MockIOrderRepositoryMockRecorder) GetPage(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockIOrderRepositoryMockRecorder) GetPage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockIOrderRepository)(nil).GetPage), arg0, arg1, arg2, arg3)
+}
+
+// Iterate mocks base method
+func (m *MockIOrderRepository) Iterate(arg0 context.Context, arg1 repository.OrderFilter) (repository.IOrderIterator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Iterate", arg0, arg1)
+	ret0, _ := ret[0].(repository.IOrderIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Iterate indicates an expected call of Iterate
+func (mr *MockIOrderRepositoryMockRecorder) Iterate(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockIOrderRepository)(nil).GetPage), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Iterate", reflect.TypeOf((*MockIOrderRepository)(nil).Iterate), arg0, arg1)
 }
@@ -8,6 +8,7 @@ import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
 	model "microservice_gokit_base/src/domain/model"
+	repository "microservice_gokit_base/src/domain/repository"
 	reflect "reflect"
 )
 
@@ -35,18 +36,33 @@ func (m *MockIOrderService) EXPECT() *MockIOrderServiceMockRecorder {
 }
 
 // ChangeStatus mocks base method
-func (m *MockIOrderService) ChangeStatus(arg0 context.Context, arg1, arg2 string) (int64, error) {
+func (m *MockIOrderService) ChangeStatus(arg0 context.Context, arg1 string, arg2 model.StatusChange, arg3 repository.UpdateCondition) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ChangeStatus", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "ChangeStatus", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ChangeStatus indicates an expected call of ChangeStatus
-func (mr *MockIOrderServiceMockRecorder) ChangeStatus(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockIOrderServiceMockRecorder) ChangeStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeStatus", reflect.TypeOf((*MockIOrderService)(nil).ChangeStatus), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeStatus", reflect.TypeOf((*MockIOrderService)(nil).ChangeStatus), arg0, arg1, arg2, arg3)
+}
+
+// ChangeStatusBulk mocks base method
+func (m *MockIOrderService) ChangeStatusBulk(arg0 context.Context, arg1 repository.OrderFilter, arg2 model.StatusChange) ([]model.StatusChangeResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeStatusBulk", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.StatusChangeResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeStatusBulk indicates an expected call of ChangeStatusBulk
+func (mr *MockIOrderServiceMockRecorder) ChangeStatusBulk(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeStatusBulk", reflect.TypeOf((*MockIOrderService)(nil).ChangeStatusBulk), arg0, arg1, arg2)
 }
 
 // Count mocks base method
@@ -64,6 +80,21 @@ func (mr *MockIOrderServiceMockRecorder) Count(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockIOrderService)(nil).Count), arg0)
 }
 
+// CountFilter mocks base method
+func (m *MockIOrderService) CountFilter(arg0 context.Context, arg1 repository.OrderFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFilter", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFilter indicates an expected call of CountFilter
+func (mr *MockIOrderServiceMockRecorder) CountFilter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFilter", reflect.TypeOf((*MockIOrderService)(nil).CountFilter), arg0, arg1)
+}
+
 // Create mocks base method
 func (m *MockIOrderService) Create(arg0 context.Context, arg1 model.Order) (string, error) {
 	m.ctrl.T.Helper()
@@ -79,19 +110,49 @@ func (mr *MockIOrderServiceMockRecorder) Create(arg0, arg1 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockIOrderService)(nil).Create), arg0, arg1)
 }
 
+// CreateBatch mocks base method
+func (m *MockIOrderService) CreateBatch(arg0 context.Context, arg1 []model.Order) ([]model.CreateResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", arg0, arg1)
+	ret0, _ := ret[0].([]model.CreateResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBatch indicates an expected call of CreateBatch
+func (mr *MockIOrderServiceMockRecorder) CreateBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockIOrderService)(nil).CreateBatch), arg0, arg1)
+}
+
+// Export mocks base method
+func (m *MockIOrderService) Export(arg0 context.Context, arg1 repository.OrderFilter) (repository.IOrderIterator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", arg0, arg1)
+	ret0, _ := ret[0].(repository.IOrderIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export
+func (mr *MockIOrderServiceMockRecorder) Export(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockIOrderService)(nil).Export), arg0, arg1)
+}
+
 // GetAll mocks base method
-func (m *MockIOrderService) GetAll(arg0 context.Context) ([]*model.Order, error) {
+func (m *MockIOrderService) GetAll(arg0 context.Context, arg1 repository.OrderFilter) ([]*model.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", arg0)
+	ret := m.ctrl.Call(m, "GetAll", arg0, arg1)
 	ret0, _ := ret[0].([]*model.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll
-func (mr *MockIOrderServiceMockRecorder) GetAll(arg0 interface{}) *gomock.Call {
+func (mr *MockIOrderServiceMockRecorder) GetAll(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockIOrderService)(nil).GetAll), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockIOrderService)(nil).GetAll), arg0, arg1)
 }
 
 // GetByID mocks base method
@@ -109,17 +170,47 @@ func (mr *MockIOrderServiceMockRecorder) GetByID(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockIOrderService)(nil).GetByID), arg0, arg1)
 }
 
+// GetHistory mocks base method
+func (m *MockIOrderService) GetHistory(arg0 context.Context, arg1 string) ([]model.StatusChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1)
+	ret0, _ := ret[0].([]model.StatusChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory
+func (mr *MockIOrderServiceMockRecorder) GetHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockIOrderService)(nil).GetHistory), arg0, arg1)
+}
+
 // GetPage mocks base method
-func (m *MockIOrderService) GetPage(arg0 context.Context, arg1, arg2 int64) ([]*model.Order, error) {
+func (m *MockIOrderService) GetPage(arg0 context.Context, arg1 repository.OrderFilter, arg2, arg3 int64) ([]*model.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPage", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetPage", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]*model.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPage indicates an expected call of GetPage
-func (mr *MockIOrderServiceMockRecorder) GetPage(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockIOrderServiceMockRecorder) GetPage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockIOrderService)(nil).GetPage), arg0, arg1, arg2, arg3)
+}
+
+// Import mocks base method
+func (m *MockIOrderService) Import(arg0 context.Context, arg1 []model.Order) ([]model.CreateResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", arg0, arg1)
+	ret0, _ := ret[0].([]model.CreateResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import
+func (mr *MockIOrderServiceMockRecorder) Import(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPage", reflect.TypeOf((*MockIOrderService)(nil).GetPage), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockIOrderService)(nil).Import), arg0, arg1)
 }